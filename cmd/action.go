@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/ManudL2000/tgcom-cobra/utils"
+	"github.com/spf13/cobra"
+)
+
+/* commentCmd, uncommentCmd and toggleCmd are the commands tgcom comment, tgcom uncomment and tgcom toggle: they do
+what tgcom -a does, but the action is the name of the command and the files are its arguments */
+var commentCmd = &cobra.Command{
+	Use:   "comment FILE[:LINES]...",
+	Short: "comment the selected lines of the files",
+	Long: `comment comments the lines of every file selected with -l, -s and -e, -k or, for notebooks, --cell
+and --cell-tag. Lines can also be given after the name of each file, e.g. main.go:3-5. Lines that are
+already commented are commented again, use tgcom -f FILE --ensure commented to skip them.`,
+	Args: fileArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runAction(cmd, args, utils.ActionComment)
+	},
+}
+
+var uncommentCmd = &cobra.Command{
+	Use:   "uncomment FILE[:LINES]...",
+	Short: "uncomment the selected lines of the files",
+	Long: `uncomment removes the comment characters from the lines of every file selected with -l, -s and -e,
+-k or, for notebooks, --cell and --cell-tag. Lines can also be given after the name of each file, e.g.
+main.go:3-5. Lines that are not commented are left as they are.`,
+	Args: fileArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runAction(cmd, args, utils.ActionUncomment)
+	},
+}
+
+var toggleCmd = &cobra.Command{
+	Use:   "toggle FILE[:LINES]...",
+	Short: "comment the uncommented lines and uncomment the commented ones",
+	Long: `toggle comments every selected line that is not commented and uncomments every line that is. The
+lines are selected as in tgcom comment.`,
+	Args: fileArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runAction(cmd, args, utils.ActionToggle)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(commentCmd)
+	rootCmd.AddCommand(uncommentCmd)
+	rootCmd.AddCommand(toggleCmd)
+}
+
+/* the files are the arguments and the ones given with -f, at least one is needed unless --git-diff chooses them */
+func fileArgs(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 && !cmd.Flags().Changed("file") && !cmd.Flags().Changed("git-diff") {
+		return usageErrorf("give at least one file: tgcom %s FILE...", cmd.Name())
+	}
+	return nil
+}
+
+/* runs action on the files of the arguments and of -f */
+func runAction(cmd *cobra.Command, args []string, action utils.Action) {
+	ActionToDo = string(action)
+
+	var files []string
+	if FileToRead != "" {
+		files = strings.Split(FileToRead, ",")
+	}
+	files = append(files, args...)
+	if cmd.Flags().Changed("git-diff") {
+		// the files, if given, restrict the changed lines to those files
+		if len(files) > 0 {
+			cmd.Flags().Set("file", strings.Join(files, ","))
+		}
+		GitDiffFlags(cmd)
+		return
+	}
+	changeFiles(cmd, files, action)
+}
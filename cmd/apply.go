@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ManudL2000/tgcom-cobra/utils"
+	"github.com/spf13/cobra"
+)
+
+/* In these variables we store the arguments passed to the flags of tgcom apply */
+var ManifestVars []string
+var ReportFormat string
+
+/* applyCmd is the command tgcom apply: it reads a manifest (a yaml file with a list of operations) and executes every
+operation, printing what happened to each file */
+var applyCmd = &cobra.Command{
+	Use:   "apply MANIFEST",
+	Short: "execute the operations described in a manifest file",
+	Long: `apply reads a yaml manifest with a list of operations and executes them in order. Each operation
+selects some files (glob patterns relative to the manifest, ** matches any directory), some lines (lines,
+start-label/end-label, regex, symbol or key), an action and optionally a language. Example:
+
+	vars:
+	  block: debug
+	operations:
+	  - name: disable debug
+	    files: ["src/**/*.go"]
+	    start-label: "${block}-start"
+	    end-label: "${block}-end"
+	    action: comment
+
+Variables can be overridden with --var name=value.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		vars := map[string]string{}
+		for _, v := range ManifestVars {
+			name, value, ok := strings.Cut(v, "=")
+			if !ok {
+				exitWithError(usageErrorf("invalid variable %q: use name=value", v))
+			}
+			vars[name] = value
+		}
+
+		manifest, err := utils.LoadManifest(args[0], vars)
+		if err != nil {
+			exitWithError(err)
+		}
+		results := manifest.Apply(filepath.Dir(args[0]), DryRun)
+
+		failed, succeeded := false, false
+		for _, result := range results {
+			failed = failed || result.Failed()
+			for _, file := range result.Files {
+				succeeded = succeeded || file.Error == ""
+			}
+		}
+		switch ReportFormat {
+		case "json":
+			out, _ := json.MarshalIndent(results, "", "  ")
+			fmt.Println(string(out))
+		default:
+			printApplyResults(results)
+		}
+		// the errors are in the report, only the exit code tells whether some operation worked
+		if failed && succeeded {
+			os.Exit(ExitPartial)
+		}
+		if failed {
+			os.Exit(ExitFailure)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+	applyCmd.Flags().StringArrayVar(&ManifestVars, "var", nil, "pass name=value to set a variable of the manifest")
+	applyCmd.Flags().StringVar(&ReportFormat, "format", "table", "pass table or json to choose the format of the report")
+}
+
+/* prints a line for each operation and a line for each of its files */
+func printApplyResults(results []utils.OperationResult) {
+	for _, result := range results {
+		status := "ok"
+		if result.Failed() {
+			status = "FAILED"
+		}
+		fmt.Printf("%-6s  %s\n", status, result.Name)
+		if result.Error != "" {
+			fmt.Printf("        %s\n", result.Error)
+		}
+		for _, file := range result.Files {
+			if file.Error != "" {
+				fmt.Printf("        %s: %s\n", file.File, file.Error)
+			} else {
+				fmt.Printf("        %s: %d lines changed\n", file.File, file.Changed)
+			}
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ManudL2000/tgcom-cobra/utils"
+	"github.com/spf13/cobra"
+)
+
+/* In these variables we store the arguments passed to the flags of tgcom check */
+var PolicyFile string
+var CheckManifestFile string
+var CheckBranch string
+var JUnitFile string
+var CheckFormat string
+
+/* checkCmd is the command tgcom check: it validates the labels and blocks of a tree without modifying it, so that it
+can be run in CI */
+var checkCmd = &cobra.Command{
+	Use:   "check [FILE|DIR]...",
+	Short: "validate labels, blocks and policy without modifying files",
+	Long: `check scans the given files and directories (the current directory by default) and reports:
+  - start labels without end label and end labels without start label
+  - blocks nested in other blocks or overlapping them
+  - blocks that are not in the state required by the policy on a protected branch
+  - files of the manifest given with --manifest whose language is not supported
+The policy is the policy section of .tgcom.yaml, or the file given with --policy. With --format github or
+gitlab the findings are printed as annotations of GitHub Actions or as a GitLab code quality report.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := checkFormat(CheckFormat); err != nil {
+			exitWithError(err)
+		}
+		if len(args) == 0 {
+			args = []string{"."}
+		}
+		policy := loadPolicy()
+		branch := CheckBranch
+		if branch == "" && policy != nil && len(policy.ProtectedBranches) > 0 {
+			var err error
+			if branch, err = utils.CurrentBranch(); err != nil {
+				exitWithError(err)
+			}
+		}
+
+		files, errs := utils.CollectFiles(args)
+		sources, readErrs := utils.ReadSources(files)
+		errs = append(errs, readErrs...)
+		for _, err := range errs {
+			printError(err)
+		}
+		findings := utils.CheckSources(sources, policy, branch)
+
+		if CheckManifestFile != "" {
+			manifest, err := utils.LoadManifest(CheckManifestFile, nil)
+			if err != nil {
+				exitWithError(err)
+			}
+			findings = append(findings, utils.CheckManifest(manifest, filepath.Dir(CheckManifestFile))...)
+		}
+
+		printFindings(findings)
+		if JUnitFile != "" {
+			if err := writeJUnit(JUnitFile, files, findings); err != nil {
+				exitWithError(err)
+			}
+		}
+		if len(findings) > 0 {
+			os.Exit(ExitCheckFailed)
+		}
+		if len(errs) > 0 {
+			os.Exit(exitCode(errs[0]))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+	checkCmd.Flags().StringVar(&PolicyFile, "policy", "", "pass a policy file to use instead of the policy of .tgcom.yaml")
+	checkCmd.Flags().StringVar(&CheckManifestFile, "manifest", "", "pass a manifest to check that all its files are supported")
+	checkCmd.Flags().StringVar(&CheckBranch, "branch", "", "pass the branch to check the policy for (default the current branch)")
+	checkCmd.Flags().StringVar(&CheckFormat, "format", "text", "pass text, json, github or gitlab to choose the format of the findings")
+	checkCmd.Flags().StringVar(&JUnitFile, "junit", "", "pass a file where to write a JUnit XML report")
+}
+
+/* returns the policy given with --policy or the one of the project configuration, nil if there is none */
+func loadPolicy() *utils.Policy {
+	if PolicyFile != "" {
+		policy, err := utils.LoadPolicy(PolicyFile)
+		if err != nil {
+			exitWithError(err)
+		}
+		return policy
+	}
+	return Settings.Policy
+}
+
+/* the formats of the findings of tgcom check */
+var checkFormats = []string{"text", "json", "github", "gitlab"}
+
+/* returns a usage error if format is not one of checkFormats, so that a typo does not print the findings as text in a
+CI job that expects a report */
+func checkFormat(format string) error {
+	if !contains(checkFormats, format) {
+		return usageErrorf("invalid format %q: use text, json, github or gitlab", format)
+	}
+	return nil
+}
+
+func printFindings(findings []utils.Finding) {
+	switch CheckFormat {
+	case "github":
+		// https://docs.github.com/en/actions/using-workflows/workflow-commands-for-github-actions#setting-an-error-message
+		for _, f := range findings {
+			if f.Line > 0 {
+				fmt.Printf("::error file=%s,line=%d,title=tgcom %s::%s\n", f.File, f.Line, f.Rule, f.Message)
+			} else {
+				fmt.Printf("::error file=%s,title=tgcom %s::%s\n", f.File, f.Rule, f.Message)
+			}
+		}
+	case "gitlab":
+		// https://docs.gitlab.com/ee/ci/testing/code_quality.html#implement-a-custom-tool
+		type lines struct {
+			Begin int `json:"begin"`
+		}
+		type location struct {
+			Path  string `json:"path"`
+			Lines lines  `json:"lines"`
+		}
+		type issue struct {
+			Description string   `json:"description"`
+			CheckName   string   `json:"check_name"`
+			Fingerprint string   `json:"fingerprint"`
+			Severity    string   `json:"severity"`
+			Location    location `json:"location"`
+		}
+		issues := []issue{}
+		for _, f := range findings {
+			begin := f.Line
+			if begin < 1 {
+				begin = 1
+			}
+			sum := sha1.Sum([]byte(fmt.Sprintf("%s:%d:%s:%s", f.File, f.Line, f.Rule, f.Message)))
+			issues = append(issues, issue{Description: f.Message, CheckName: f.Rule, Fingerprint: hex.EncodeToString(sum[:]),
+				Severity: "major", Location: location{Path: f.File, Lines: lines{Begin: begin}}})
+		}
+		out, _ := json.MarshalIndent(issues, "", "  ")
+		fmt.Println(string(out))
+	case "json":
+		if findings == nil {
+			findings = []utils.Finding{}
+		}
+		out, _ := json.MarshalIndent(findings, "", "  ")
+		fmt.Println(string(out))
+	case "text":
+		for _, f := range findings {
+			fmt.Printf("%s: %s (%s)\n", findingLocation(f), f.Message, f.Rule)
+		}
+	}
+}
+
+/* writes a JUnit report with a test case for each file checked, failed if the file has findings */
+func writeJUnit(path string, files []string, findings []utils.Finding) error {
+	type failure struct {
+		Message string `xml:"message,attr"`
+		Type    string `xml:"type,attr"`
+		Text    string `xml:",chardata"`
+	}
+	type testCase struct {
+		Name      string    `xml:"name,attr"`
+		ClassName string    `xml:"classname,attr"`
+		Failures  []failure `xml:"failure"`
+	}
+	type testSuite struct {
+		XMLName  xml.Name   `xml:"testsuite"`
+		Name     string     `xml:"name,attr"`
+		Tests    int        `xml:"tests,attr"`
+		Failures int        `xml:"failures,attr"`
+		Cases    []testCase `xml:"testcase"`
+	}
+
+	byFile := map[string][]utils.Finding{}
+	names := append([]string{}, files...)
+	for _, f := range findings {
+		if _, ok := byFile[f.File]; !ok && !contains(files, f.File) {
+			names = append(names, f.File)
+		}
+		byFile[f.File] = append(byFile[f.File], f)
+	}
+
+	suite := testSuite{Name: "tgcom check"}
+	for _, name := range names {
+		tc := testCase{Name: name, ClassName: "tgcom.check"}
+		for _, f := range byFile[name] {
+			tc.Failures = append(tc.Failures, failure{Message: f.Message, Type: f.Rule, Text: findingLocation(f) + ": " + f.Message})
+		}
+		if len(tc.Failures) > 0 {
+			suite.Failures++
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	suite.Tests = len(suite.Cases)
+
+	out, err := xml.MarshalIndent(suite, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append([]byte(xml.Header), append(out, '\n')...), 0644)
+}
+
+/* returns file:line, or only the file for the findings about the whole file */
+func findingLocation(f utils.Finding) string {
+	if f.Line > 0 {
+		return fmt.Sprintf("%s:%d", f.File, f.Line)
+	}
+	return f.File
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
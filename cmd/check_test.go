@@ -0,0 +1,16 @@
+package cmd
+
+import "testing"
+
+func TestCheckFormat(t *testing.T) {
+	for _, format := range checkFormats {
+		if err := checkFormat(format); err != nil {
+			t.Errorf("format %s: %v", format, err)
+		}
+	}
+	for _, format := range []string{"", "JSON", "sarif", "githb"} {
+		if err := checkFormat(format); exitCode(err) != ExitUsage {
+			t.Errorf("format %q gives %v, want a usage error", format, err)
+		}
+	}
+}
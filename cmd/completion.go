@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ManudL2000/tgcom-cobra/utils"
+	"github.com/spf13/cobra"
+)
+
+/* The script of the shell completion is printed by tgcom completion bash|zsh|fish|powershell (the command is added by
+cobra, see tgcom completion SHELL --help to install it). The functions in this file give the dynamic suggestions: the
+supported files for -f and for the files of the commands, the labels found in the chosen files for -s and -e, and the
+profiles of .tgcom.yaml */
+
+/* registers the completion functions, it is called by the init of root.go after the flags have been defined */
+func registerCompletions() {
+	rootCmd.RegisterFlagCompletionFunc("file", completeFiles)
+	rootCmd.RegisterFlagCompletionFunc("start-label", completeLabels(true))
+	rootCmd.RegisterFlagCompletionFunc("end-label", completeLabels(false))
+	for _, c := range []*cobra.Command{rootCmd, pickCmd} {
+		c.RegisterFlagCompletionFunc("action", cobra.FixedCompletions([]string{"comment", "uncomment", "toggle"}, cobra.ShellCompDirectiveNoFileComp))
+	}
+	rootCmd.RegisterFlagCompletionFunc("ensure", cobra.FixedCompletions([]string{"commented", "uncommented"}, cobra.ShellCompDirectiveNoFileComp))
+	rootCmd.RegisterFlagCompletionFunc("log-format", cobra.FixedCompletions([]string{"text", "json"}, cobra.ShellCompDirectiveNoFileComp))
+	rootCmd.RegisterFlagCompletionFunc("backup", cobra.FixedCompletions([]string{"none", "temp", "keep", "numbered"}, cobra.ShellCompDirectiveNoFileComp))
+	rootCmd.RegisterFlagCompletionFunc("backup-dir", cobra.FixedCompletions(nil, cobra.ShellCompDirectiveFilterDirs))
+
+	for _, c := range []*cobra.Command{commentCmd, uncommentCmd, toggleCmd} {
+		c.ValidArgsFunction = completeFiles
+	}
+	for _, c := range []*cobra.Command{enableCmd, disableCmd, watchCmd} {
+		c.ValidArgsFunction = completeProfiles
+	}
+}
+
+/* suggests the files whose extension is supported */
+func completeFiles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return utils.SupportedExtensions(), cobra.ShellCompDirectiveFilterFileExt
+}
+
+/* suggests the start labels (or the end labels) of the files given with -f and as arguments */
+func completeLabels(start bool) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		var files []string
+		if FileToRead != "" {
+			files = strings.Split(FileToRead, ",")
+		}
+		files = append(files, args...)
+
+		var suggestions []string
+		for _, file := range files {
+			file, _, _ = strings.Cut(file, ":")
+			data, err := os.ReadFile(file)
+			if err != nil {
+				continue
+			}
+			labels, err := utils.FileLabels(file, strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"), start)
+			if err != nil {
+				continue
+			}
+			suggestions = append(suggestions, filterPrefix(labels, toComplete)...)
+		}
+		return suggestions, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+/* suggests the profiles of the project configuration, for the first argument */
+func completeProfiles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	// the configuration is loaded again because --config is parsed after Settings, during the completion
+	config, _, err := utils.LoadLayeredConfig(ConfigFile, os.LookupEnv)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var names []string
+	for name := range config.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return filterPrefix(names, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+/* returns the values that start with prefix */
+func filterPrefix(values []string, prefix string) []string {
+	var filtered []string
+	for _, value := range values {
+		if strings.HasPrefix(value, prefix) {
+			filtered = append(filtered, value)
+		}
+	}
+	return filtered
+}
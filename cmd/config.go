@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/ManudL2000/tgcom-cobra/utils"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+/* Settings is the effective configuration: the defaults, the user configuration, the project configuration (the one
+given with --config or the first .tgcom.yaml found going up from the current directory) and the TGCOM_* variables.
+It is loaded before every command, the flags given on the command line override it */
+var Settings *utils.Config
+
+/* files read to build Settings */
+var SettingsSources []string
+
+/* In these variables we store the arguments passed to the flags --backup and --backup-dir */
+var BackupPolicy string
+var BackupDir string
+
+/* configCmd is the command tgcom config, it only groups its subcommands */
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "show the configuration of tgcom",
+	Long: `config groups the commands about the configuration, that is read from the user configuration
+(e.g. ~/.config/tgcom/config.yaml), from the first .tgcom.yaml found going up from the current directory
+(or from --config, or TGCOM_CONFIG) and from the TGCOM_* environment variables, each one overriding the
+previous. The flags of the command line override them all.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "print the effective configuration",
+	Long: `show prints the configuration obtained by merging the user configuration, the project configuration,
+the TGCOM_* environment variables and the flags that have been given, preceded by the files it was read from.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		data, err := yaml.Marshal(Settings)
+		if err != nil {
+			exitWithError(err)
+		}
+		for _, source := range SettingsSources {
+			fmt.Printf("# from %s\n", source)
+		}
+		fmt.Print(string(data))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configShowCmd)
+	rootCmd.PersistentFlags().StringVar(&BackupPolicy, "backup", "temp", "pass none, temp, keep or numbered to choose whether the backup of a modified file is removed, kept as FILE.tgcom.bak or kept as FILE.tgcom.bak.N (default from the configuration)")
+	rootCmd.PersistentFlags().StringVar(&BackupDir, "backup-dir", "", "pass a directory where to put the backups instead of next to the files")
+	// the logging is set up first, so that the logs of the configuration follow -v, --quiet and --log-format
+	cobra.OnInitialize(setupLogging, loadSettings)
+}
+
+/* loads Settings, applies the flags given on the command line over it and makes it effective. A flag that has not
+been given takes its value from the configuration, e.g. -a takes the default action */
+func loadSettings() {
+	config, sources, err := utils.LoadLayeredConfig(ConfigFile, os.LookupEnv)
+	if err != nil {
+		exitWithError(err)
+	}
+	// -a is a flag of tgcom and of tgcom pick, only the flags of the command that runs are parsed
+	if rootCmd.Flags().Changed("action") || pickCmd.Flags().Changed("action") {
+		action, err := utils.ParseAction(ActionToDo)
+		if err != nil {
+			exitWithError(err)
+		}
+		config.Action = action
+	}
+	ActionToDo = string(config.Action)
+	if rootCmd.PersistentFlags().Changed("backup") {
+		backup, err := utils.ParseBackupPolicy(BackupPolicy)
+		if err != nil {
+			exitWithError(err)
+		}
+		config.Backup = backup
+	}
+	if rootCmd.PersistentFlags().Changed("backup-dir") {
+		config.BackupDir = BackupDir
+	}
+
+	config.Apply()
+	Settings, SettingsSources = config, sources
+	slog.Info("configuration loaded", "files", sources)
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ManudL2000/tgcom-cobra/utils"
+)
+
+/* Exit codes of tgcom, the same for every command. 64 and 74 are EX_USAGE and EX_IOERR of sysexits.h */
+const (
+	/* an error that has no other code */
+	ExitFailure = 1
+	/* --ensure (and --git-diff with --ensure) had to modify some lines to bring them in the desired state, or with -d
+would have to */
+	ExitChanged = 2
+	/* the selection did not match: a line out of range, a label, key, symbol or cell that is not found */
+	ExitNoMatch = 3
+	/* some files were modified and others failed, the errors are printed on stderr */
+	ExitPartial = 4
+	/* tgcom check, tgcom status --expect or tgcom hook run found a problem */
+	ExitCheckFailed = 5
+	/* invalid flags or arguments: a bad range, action or state, an unsupported file or a missing flag */
+	ExitUsage = 64
+	/* a file could not be read or written */
+	ExitIO = 74
+)
+
+/* the exit codes as shown in the help */
+const exitCodesHelp = `Exit codes:
+  0   success
+  1   generic failure
+  2   --ensure modified some lines (with -d: would modify them)
+  3   no match: line out of range, label, key, symbol or cell not found
+  4   partial failure: some files failed, the others were modified
+  5   check failed (check, status --expect, hook run)
+  64  usage error: invalid flags or arguments
+  74  I/O error: a file could not be read or written`
+
+/* returns the exit code for the type of err (see utils.UsageError, utils.NoMatchError and utils.IOError) */
+func exitCode(err error) int {
+	var usage *utils.UsageError
+	var noMatch *utils.NoMatchError
+	var io *utils.IOError
+	switch {
+	case errors.As(err, &usage):
+		return ExitUsage
+	case errors.As(err, &noMatch):
+		return ExitNoMatch
+	case errors.As(err, &io):
+		return ExitIO
+	}
+	return ExitFailure
+}
+
+/* returns an error for invalid flags or arguments, that exits with ExitUsage */
+func usageErrorf(format string, args ...interface{}) error {
+	return &utils.UsageError{Err: fmt.Errorf(format, args...)}
+}
+
+/* prints the error on stderr */
+func printError(err error) {
+	fmt.Fprintf(os.Stderr, "tgcom: %v\n", err)
+}
+
+/* prints the error on stderr and exits with its exit code */
+func exitWithError(err error) {
+	printError(err)
+	os.Exit(exitCode(err))
+}
+
+/* prints the errors of the files on stderr and exits if there is one: with ExitPartial if only some of the total files
+failed, with the exit code of the first error if all of them failed */
+func exitWithErrors(errs []error, total int) {
+	for _, err := range errs {
+		printError(err)
+	}
+	if len(errs) > 0 {
+		os.Exit(filesExitCode(errs, total))
+	}
+}
+
+/* returns the exit code for the errors of some of the total files, see exitWithErrors */
+func filesExitCode(errs []error, total int) int {
+	switch {
+	case len(errs) == 0:
+		return 0
+	case len(errs) < total:
+		return ExitPartial
+	default:
+		return exitCode(errs[0])
+	}
+}
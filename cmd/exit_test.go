@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ManudL2000/tgcom-cobra/utils"
+)
+
+func TestExitCode(t *testing.T) {
+	_, actionErr := utils.ParseAction("delete")
+	_, _, rangeErr := utils.Transform([]byte("a\n"), "Bash", utils.Selection{Lines: "3"}, utils.ActionComment)
+	ioErr := utils.DefaultEngine.ChangeFileLine("missing.go", "1", utils.ActionComment, false)
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"usage", usageErrorf("missing flag"), ExitUsage},
+		{"invalid action", actionErr, ExitUsage},
+		{"line out of range", rangeErr, ExitNoMatch},
+		{"missing file", ioErr, ExitIO},
+		{"wrapped", fmt.Errorf("main.go: %w", rangeErr), ExitNoMatch},
+		{"other", errors.New("failure"), ExitFailure},
+	}
+	for _, test := range tests {
+		if got := exitCode(test.err); got != test.want {
+			t.Errorf("%s: exitCode(%v) = %d, want %d", test.name, test.err, got, test.want)
+		}
+	}
+}
+
+func TestFilesExitCode(t *testing.T) {
+	noMatch := fmt.Errorf("b.go: %w", &utils.NoMatchError{Err: errors.New("label not found")})
+	tests := []struct {
+		name  string
+		errs  []error
+		total int
+		want  int
+	}{
+		{"no errors", nil, 2, 0},
+		{"some files failed", []error{noMatch}, 2, ExitPartial},
+		{"every file failed", []error{noMatch, errors.New("failure")}, 2, ExitNoMatch},
+		{"single file", []error{noMatch}, 1, ExitNoMatch},
+	}
+	for _, test := range tests {
+		if got := filesExitCode(test.errs, test.total); got != test.want {
+			t.Errorf("%s: filesExitCode = %d, want %d", test.name, got, test.want)
+		}
+	}
+}
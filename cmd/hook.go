@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ManudL2000/tgcom-cobra/utils"
+	"github.com/spf13/cobra"
+)
+
+/* In these variables we store the arguments passed to the flags of tgcom hook */
+var HookFix bool
+var HookForce bool
+
+/* hookCmd is the command tgcom hook, that groups the commands to install, remove and run the git pre-commit hook */
+var hookCmd = &cobra.Command{
+	Use:   "hook",
+	Short: "install and run the git pre-commit hook",
+}
+
+var hookInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "install a pre-commit hook that checks the staged files",
+	Long: `install writes a git pre-commit hook that runs "tgcom hook run" before every commit. With --fix
+the hook comments (or uncomments) the blocks that violate the policy instead of stopping the commit.
+To use the pre-commit framework instead, add this repository to .pre-commit-config.yaml with the hook
+tgcom-check or tgcom-fix.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		executable, err := os.Executable()
+		if err != nil {
+			exitWithError(err)
+		}
+		path, err := utils.InstallHook(executable, HookFix, HookForce)
+		if err != nil {
+			exitWithError(err)
+		}
+		fmt.Printf("installed %s\n", path)
+	},
+}
+
+var hookUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "remove the pre-commit hook installed by tgcom",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		path, err := utils.UninstallHook()
+		if err != nil {
+			exitWithError(err)
+		}
+		fmt.Printf("removed %s\n", path)
+	},
+}
+
+var hookRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "check the staged content of the files (run by the pre-commit hook)",
+	Long: `run checks the content of the files in the git index, that is what is going to be committed, with
+the same rules of tgcom check. With --fix the blocks that violate the policy are fixed in the index.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		root, err := utils.GitRoot()
+		if err == nil {
+			err = os.Chdir(root)
+		}
+		if err != nil {
+			exitWithError(err)
+		}
+
+		policy := loadPolicy()
+		branch := ""
+		if policy != nil && len(policy.ProtectedBranches) > 0 {
+			if branch, err = utils.CurrentBranch(); err != nil {
+				exitWithError(err)
+			}
+		}
+
+		sources, err := utils.StagedSources()
+		if err != nil {
+			exitWithError(err)
+		}
+		if HookFix {
+			fixed, err := utils.FixStaged(sources, policy, branch)
+			for _, f := range fixed {
+				fmt.Fprintf(os.Stderr, "tgcom: %s: %s\n", findingLocation(f), f.Message)
+			}
+			if err != nil {
+				exitWithError(err)
+			}
+		}
+
+		findings := utils.CheckSources(sources, policy, branch)
+		for _, f := range findings {
+			fmt.Fprintf(os.Stderr, "tgcom: %s: %s (%s)\n", findingLocation(f), f.Message, f.Rule)
+		}
+		if len(findings) > 0 {
+			fmt.Fprintln(os.Stderr, "tgcom: commit stopped, fix the problems above or commit with --no-verify")
+			os.Exit(ExitCheckFailed)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(hookCmd)
+	hookCmd.AddCommand(hookInstallCmd, hookUninstallCmd, hookRunCmd)
+	hookInstallCmd.Flags().BoolVar(&HookFix, "fix", false, "install a hook that fixes the blocks that violate the policy")
+	hookInstallCmd.Flags().BoolVar(&HookForce, "force", false, "replace a pre-commit hook that was not installed by tgcom")
+	hookRunCmd.Flags().BoolVar(&HookFix, "fix", false, "fix the blocks that violate the policy in the index")
+	hookRunCmd.Flags().StringVar(&PolicyFile, "policy", "", "pass a policy file to use instead of the policy of .tgcom.yaml")
+}
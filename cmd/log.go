@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"log/slog"
+	"os"
+
+	"github.com/ManudL2000/tgcom-cobra/utils"
+)
+
+/* In these variables we store the arguments passed to the flags -v, --quiet and --log-format */
+var Verbosity int
+var Quiet bool
+var LogFormat string
+
+func init() {
+	rootCmd.PersistentFlags().CountVarP(&Verbosity, "verbose", "v", "pass -v to log what tgcom does (files written, timings) and -vv to log also languages, selections and temporary files")
+	rootCmd.PersistentFlags().BoolVar(&Quiet, "quiet", false, "pass quiet to log only the errors, without warnings")
+	rootCmd.PersistentFlags().StringVar(&LogFormat, "log-format", "text", "pass text or json to choose the format of the logs")
+}
+
+/* creates the logger of tgcom and of the utils package. The logs always go on stderr, so that stdout keeps only the
+output of the commands (e.g. dry runs and reports). By default only warnings and errors are logged. It runs before
+every command, before loadSettings */
+func setupLogging() {
+	setupLoggingLevel(slog.LevelWarn)
+}
+
+/* same as setupLogging, but without -v and --quiet the logs of level defaultLevel and above are logged. Commands
+whose output is the log itself, like tgcom watch, call it with slog.LevelInfo */
+func setupLoggingLevel(defaultLevel slog.Level) {
+	level := defaultLevel
+	switch {
+	case Quiet:
+		level = slog.LevelError
+	case Verbosity == 1:
+		level = slog.LevelInfo
+	case Verbosity > 1:
+		level = slog.LevelDebug
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch LogFormat {
+	case "text":
+		handler = slog.NewTextHandler(os.Stderr, options)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, options)
+	default:
+		exitWithError(usageErrorf("invalid log format %q: use text or json", LogFormat))
+	}
+
+	logger := slog.New(handler)
+	slog.SetDefault(logger)
+	utils.Logger = logger
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/ManudL2000/tgcom-cobra/utils"
+	"github.com/spf13/cobra"
+)
+
+/* lspCmd is the command tgcom lsp, that runs a language server on the standard input and output */
+var lspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "run a language server to comment and uncomment code from any editor",
+	Long: `lsp speaks the Language Server Protocol on the standard input and output. Configure it in the
+editor as a language server with the command "tgcom lsp": it offers the code actions "Comment selection",
+"Uncomment selection" and "Toggle tgcom block NAME", and a symbol and a code lens for every named block.
+The server works on the buffers of the editor and never writes the files itself.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := utils.ServeLSP(os.Stdin, os.Stdout); err != nil {
+			exitWithError(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lspCmd)
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ManudL2000/tgcom-cobra/utils"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+/* pickCmd is the command tgcom pick, that lets the user choose the lines to modify in a terminal UI */
+var pickCmd = &cobra.Command{
+	Use:   "pick FILE",
+	Short: "choose the lines to comment or uncomment in a terminal UI",
+	Long: `pick shows the file in the terminal, with the commented lines dimmed and the labels highlighted.
+Move with the arrows (or j and k), start a range with space, select the labelled block under the
+cursor with b and jump to the next block with tab. Choose the action with c (comment), u (uncomment)
+or t (toggle), look at the result with p, then press enter to apply it or q to cancel. The equivalent
+tgcom command is printed at the end.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		picker, err := utils.NewPicker(args[0])
+		if err != nil {
+			exitWithError(err)
+		}
+		if cmd.Flags().Changed("action") {
+			if picker.Action, err = utils.ParseAction(ActionToDo); err != nil {
+				exitWithError(err)
+			}
+		}
+		if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+			exitWithError(usageErrorf("pick needs a terminal"))
+		}
+
+		if err := runPicker(picker); err != nil {
+			exitWithError(err)
+		}
+		if _, apply := picker.Done(); !apply {
+			fmt.Println("cancelled")
+			return
+		}
+		if DryRun {
+			fmt.Printf("tgcom -f %s -l %s -a %s\n", picker.File, picker.Lines(), picker.Action)
+			return
+		}
+		n, err := picker.Apply()
+		if err != nil {
+			exitWithError(err)
+		}
+		fmt.Printf("%s: %d lines changed (tgcom -f %s -l %s -a %s)\n", picker.File, n, picker.File, picker.Lines(), picker.Action)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pickCmd)
+	pickCmd.Flags().StringVarP(&ActionToDo, "action", "a", "toggle", "pass the action chosen when the picker starts (default from the configuration)")
+}
+
+/* puts the terminal in raw mode on the alternate screen and passes the keys to the picker until the user has finished */
+func runPicker(picker *utils.Picker) error {
+	fd := int(os.Stdin.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(fd, state)
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer fmt.Print("\x1b[?25h\x1b[?1049l")
+
+	buf := make([]byte, 16)
+	for {
+		width, height, err := term.GetSize(int(os.Stdout.Fd()))
+		if err != nil || width <= 0 || height <= 0 {
+			width, height = 80, 24
+		}
+		fmt.Print(picker.Render(width, height))
+
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return err
+		}
+		picker.HandleKey(keyName(buf[:n]))
+		if finished, _ := picker.Done(); finished {
+			return nil
+		}
+	}
+}
+
+/* returns the name of the key read from the terminal, see utils.Picker */
+func keyName(input []byte) string {
+	switch string(input) {
+	case "\x1b[A", "\x1bOA":
+		return "up"
+	case "\x1b[B", "\x1bOB":
+		return "down"
+	case "\x1b[5~":
+		return "pgup"
+	case "\x1b[6~":
+		return "pgdown"
+	case "\x1b[H", "\x1bOH", "\x1b[1~":
+		return "home"
+	case "\x1b[F", "\x1bOF", "\x1b[4~":
+		return "end"
+	case "\t":
+		return "tab"
+	case "\r", "\n":
+		return "enter"
+	case "\x1b":
+		return "esc"
+	case "\x03":
+		return "ctrl-c"
+	}
+	return string(input)
+}
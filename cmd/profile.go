@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ManudL2000/tgcom-cobra/utils"
+	"github.com/spf13/cobra"
+)
+
+/* enableCmd and disableCmd are the commands tgcom enable and tgcom disable: they switch on (uncomment) or off (comment)
+every block of a profile declared in the project configuration file */
+var enableCmd = &cobra.Command{
+	Use:   "enable PROFILE",
+	Short: "uncomment all the blocks of a profile",
+	Long: `enable uncomments every block of a profile declared in .tgcom.yaml (blocks marked with invert
+are commented instead). Blocks that are already enabled are not modified.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		setProfile(args[0], true)
+	},
+}
+
+var disableCmd = &cobra.Command{
+	Use:   "disable PROFILE",
+	Short: "comment all the blocks of a profile",
+	Long: `disable comments every block of a profile declared in .tgcom.yaml (blocks marked with invert
+are uncommented instead). Blocks that are already disabled are not modified.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		setProfile(args[0], false)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(enableCmd)
+	rootCmd.AddCommand(disableCmd)
+}
+
+/* sets the profile of the configuration */
+func setProfile(name string, enable bool) {
+	results, err := utils.SetProfile(Settings, ".", name, enable, DryRun)
+	for _, result := range results {
+		status := "already " + string(result.State)
+		if result.Changed {
+			status = "now " + string(result.State)
+		}
+		fmt.Printf("%s: block %q is %s\n", result.File, result.Block, status)
+	}
+	if err != nil {
+		exitWithError(err)
+	}
+}
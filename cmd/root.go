@@ -3,11 +3,13 @@ package cmd
 import (
 	"os"
 	"fmt"
+	"log/slog"
+	"path/filepath"
 	"github.com/ManudL2000/tgcom-cobra/utils"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
+	"strconv"
 	"strings"
-	"log"
 )
 
 /* In these variables we store the arguments passed to flags -f, -l, -d and -a */
@@ -17,31 +19,45 @@ var DryRun bool
 var ActionToDo string
 var StartLabel string
 var EndLabel string
+var CellToRead string
+var CellTag string
+var KeyToRead string
+var ConfigFile string
+var EnsureState string
+var GitDiffRef string
 
 /* rootCmd is the command tgcom. "Use" is the name of the command, "Short" is a brief description of the command, "Long
 is a longer description of the command, Run is the action that must be executed when command tgcom is called" */
 var rootCmd = &cobra.Command{
-	Use:   "ciaoo",
-	Short: "ciaoo is a verison of tgcom that uses cobra-cli toolkit",
-	Long: `A longer description that spans multiple lines and likely contains
-    examples and usage of using your application. For example:
-
-	ciaoo is a CLI library written in Go that allows users to
-	comment or uncomment pieces of code. It support many different
-	languages including Go, C, Java, Python, Bash and many others....`,
-	
+	Use:   "tgcom",
+	Short: "tgcom comments and uncomments pieces of code",
+	Long: `tgcom is a CLI tool written in Go that allows users to comment or uncomment pieces of code.
+It supports many different languages including Go, C, Java, Python, Bash and many others.
+For example:
+
+	tgcom comment main.go -l 3-5
+	tgcom uncomment main.go config.yaml:10-12
+	tgcom toggle main.go -s debug-start -e debug-end
+	tgcom -f main.go -l 3-5 -a toggle`,
+	Args: rootArgs,
 	Run: func(cmd *cobra.Command, args []string) {
 		/* If user did not call any flag then print basic info of Usage function and exit */
 		if noFlagsGiven(cmd) {
 			customUsageFunc(cmd)
-			os.Exit(1)
+			os.Exit(ExitUsage)
+		}
+		/* the value given to -d as in "tgcom -d true", see rootArgs */
+		if len(args) == 1 {
+			DryRun, _ = strconv.ParseBool(args[0])
 		}
 
 		/* Otherwise user need to pass something to flag -f. If this does not happen print an error
 		message and exit  */
-		if !cmd.Flags().Changed("file") {
-			fmt.Println("Provide a valid file with the flag -f or pass it through the pipeline")
-			os.Exit(1)
+		if !cmd.Flags().Changed("file") && !cmd.Flags().Changed("git-diff") {
+			exitWithError(usageErrorf("Provide a valid file with the flag -f or pass it through the pipeline"))
+		}
+		if _, err := utils.ParseAction(ActionToDo); err != nil {
+			exitWithError(err)
 		}
 		/* If some arguments have been passed to -f flag then process the arguments of the flag with
 		the following function */
@@ -49,11 +65,13 @@ var rootCmd = &cobra.Command{
 	},
 }
 
-/* the one command used to run the main function (set by default by cobra-cli) */
+/* the one command used to run the main function (set by default by cobra-cli). The errors returned by cobra are
+about the flags and the arguments, so they exit with ExitUsage */
 func Execute() {
+	rootCmd.SilenceErrors = true
 	err := rootCmd.Execute()
 	if err != nil {
-		os.Exit(1)
+		exitWithError(usageErrorf("%v", err))
 	}
 }
 
@@ -68,9 +86,33 @@ func init() {
 	rootCmd.PersistentFlags().StringVarP(&FileToRead, "file", "f", "", "pass argument to the flag and will print file content")
     rootCmd.PersistentFlags().StringVarP(&LineToRead, "line", "l", "", "pass argument to line flag and will print the line specified")
 	rootCmd.PersistentFlags().BoolVarP(&DryRun, "dry-run", "d", false, "pass argument to dry-run flag and will print the result")
-	rootCmd.PersistentFlags().StringVarP(&ActionToDo, "action", "a", "toggle", "pass argument to action to comment/uncomment/toggle some lines")
+	/* -a and --ensure are only flags of tgcom: the comment, uncomment and toggle commands are the action themselves */
+	rootCmd.Flags().StringVarP(&ActionToDo, "action", "a", "toggle", "pass argument to action to comment/uncomment/toggle some lines (default from the configuration)")
 	rootCmd.PersistentFlags().StringVarP(&StartLabel, "start-label", "s", "", "pass argument to start-label to modify lines after start-label")
 	rootCmd.PersistentFlags().StringVarP(&EndLabel, "end-label", "e", "", "pass argument to end-label to modify lines after end-label")
+	rootCmd.PersistentFlags().StringVar(&CellToRead, "cell", "", "pass a cell number or a range of cells (starting from 1) to modify in a jupyter notebook")
+	rootCmd.PersistentFlags().StringVarP(&KeyToRead, "key", "k", "", "pass the path of a key (e.g. server.debug) to modify it in yaml, toml, ini and .env files")
+	rootCmd.Flags().StringVar(&EnsureState, "ensure", "", "pass commented or uncommented to bring the lines in that state instead of doing -a action, exits with 2 if some line was (or with -d would be) modified")
+	rootCmd.PersistentFlags().StringVar(&ConfigFile, "config", "", "pass the path of the project configuration file (default the first .tgcom.yaml found going up from the current directory)")
+	rootCmd.PersistentFlags().StringVar(&CellTag, "cell-tag", "", "pass a tag to modify the cells of a jupyter notebook with that tag")
+	rootCmd.PersistentFlags().StringVar(&GitDiffRef, "git-diff", "", "modify only the lines changed with respect to a git revision (--git-diff=REF, default HEAD), or in the diff read from stdin with --git-diff=-")
+	rootCmd.PersistentFlags().Lookup("git-diff").NoOptDefVal = "HEAD"
+	registerCompletions()
+}
+
+/* tgcom has always been used as "tgcom -d true -f FILE" (see test/auto_test.sh): -d is a boolean flag, so its value
+is left as an argument. rootArgs accepts that value after -d, any other argument is an unknown command, as cobra says
+for the commands with subcommands */
+func rootArgs(cmd *cobra.Command, args []string) error {
+	if len(args) == 1 && cmd.Flags().Changed("dry-run") {
+		if _, err := strconv.ParseBool(args[0]); err == nil {
+			return nil
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Errorf("unknown command %q for %q", args[0], cmd.CommandPath())
+	}
+	return nil
 }
 
 /* function to see if no flag is given */
@@ -85,47 +127,220 @@ func noFlagsGiven(cmd *cobra.Command) bool {
 }
 
 /* analyze the argument of -f. If more files are given (e.g -f file1:line1,file2:line2,file3:line3) then split each
-content and pass each pair of file and corresponding line to the ChangeFile() function. Otherwise pass directly content
-of -f and -l flags. */
-/* TODO: make this function more pretty */
-
+content and pass each pair of file and corresponding line to changeFiles. Otherwise pass directly content of -f and -l
+flags. */
 func ReadFlags(cmd *cobra.Command){
-	if strings.Contains(FileToRead, ","){
-		if cmd.Flags().Changed("line"){
-			fmt.Println("Warning: when passed multiple file to flag -f don't use -l flag")
-		}
-		// TODO: add the possibility of adding labels (same start and same end label) for all the files
-		// two possibility: if both start and end label are given then there should be no lines, otherwise
-		// you should procede as before
-		if cmd.Flags().Changed("start-label") && cmd.Flags().Changed("end-label"){
-			fileInfo := strings.Split(FileToRead, ",")
-			for i:=0; i<len(fileInfo); i++ {
-					utils.ChangeFileLabel(fileInfo[i], StartLabel, EndLabel,ActionToDo, DryRun)
+	if cmd.Flags().Changed("git-diff") {
+		GitDiffFlags(cmd)
+		return
+	}
+	if cmd.Flags().Changed("ensure") {
+		EnsureFlags(cmd)
+		return
+	}
+	changeFiles(cmd, strings.Split(FileToRead, ","), utils.Action(ActionToDo))
+}
+
+/* applies action to every file, with the lines given after the name of the file (e.g. main.go:3-5) or with the lines
+selected by the flags: -k, -s and -e or -l, and --cell and --cell-tag for notebooks. The selection of every file is
+checked before modifying any of them, then every file is tried and the errors are printed at the end */
+func changeFiles(cmd *cobra.Command, files []string, action utils.Action) {
+	engine := utils.DefaultEngine
+	labels := cmd.Flags().Changed("start-label") && cmd.Flags().Changed("end-label")
+	for _, file := range files {
+		if err := checkSelectionFlags(cmd, file, labels); err != nil {
+			exitWithError(err)
+		}
+	}
+
+	var errs []error
+	for _, file := range files {
+		if skipIgnored(file) {
+			continue
+		}
+		var err error
+		if name, lines, ok := strings.Cut(file, ":"); ok {
+			file = name
+			err = engine.ChangeFileLine(name, lines, action, DryRun)
+		} else if utils.IsNotebook(file) {
+			/* in notebooks lines and labels are searched inside the cells selected with --cell and --cell-tag */
+			selection := utils.NotebookSelection{Cells: CellToRead, Tag: CellTag, Lines: LineToRead}
+			if labels {
+				selection.StartLabel, selection.EndLabel = StartLabel, EndLabel
 			}
+			err = engine.ChangeNotebook(file, selection, action, DryRun)
+		} else if cmd.Flags().Changed("key") {
+			err = engine.ChangeFileKey(file, KeyToRead, action, DryRun)
+		} else if labels {
+			err = engine.ChangeFileLabel(file, StartLabel, EndLabel, action, DryRun)
 		} else {
-			fileInfo := strings.Split(FileToRead, ",")
-			for i:=0; i<len(fileInfo); i++ {
-				if strings.Contains(fileInfo[i], ":"){
-					parts := strings.Split(fileInfo[i], ":")
-					if len(parts) != 2 {
-						log.Fatalf("invalid syntax. Use 'FileToRead:lines'")
-					}
-					utils.ChangeFileLine(parts[0], parts[1], ActionToDo, DryRun)
-				} else {
-					// HERE: insert code to say that is possible that start-end labels have been given
-					log.Fatalf("invalid syntax. Use 'FileToRead:lines'")
-				}
+			err = engine.ChangeFileLine(file, LineToRead, action, DryRun)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", file, err))
+		}
+	}
+	exitWithErrors(errs, len(files))
+}
+
+/* returns a usage error if the flags do not select the lines to modify in the file, given with its lines or not, or if
+the lines given are not valid ranges */
+func checkSelectionFlags(cmd *cobra.Command, file string, labels bool) error {
+	lines := LineToRead
+	if _, fileLines, ok := strings.Cut(file, ":"); ok {
+		lines = fileLines
+	} else if utils.IsNotebook(file) {
+		if !cmd.Flags().Changed("cell") && !cmd.Flags().Changed("cell-tag") && !labels {
+			return usageErrorf("Not specified what you want to modify: add --cell, --cell-tag or -s and -e flags")
+		}
+	} else if !cmd.Flags().Changed("key") && !labels && !cmd.Flags().Changed("line") {
+		return usageErrorf("Not specified what you want to modify: add -l flag, -k flag or -s and -e flags")
+	}
+	return checkLines(file, lines)
+}
+
+/* returns an error if a range of lines (e.g. 1-3,7) passed for file is invalid */
+func checkLines(file string, lines string) error {
+	if lines != "" {
+		for _, lineRange := range strings.Split(lines, ",") {
+			if _, _, err := utils.ParseLines(lineRange); err != nil {
+				return fmt.Errorf("%s: %w", file, err)
 			}
 		}
+	}
+	return nil
+}
+
+/* returns true if the file, given with its lines or not, matches the ignore patterns of the configuration */
+func skipIgnored(file string) bool {
+	name, _, _ := strings.Cut(file, ":")
+	if utils.IsIgnored(name) {
+		slog.Warn("file ignored by the configuration", "file", name)
+		return true
+	}
+	return false
+}
+
+/* same as ReadFlags, but with --ensure: the lines selected in each file are brought in the state passed to --ensure.
+Files already in that state are not written. The selections of all the files are checked before modifying any, then
+every file is tried and the errors are printed at the end, like in changeFiles; if there are none and some file had to
+be modified the program exits with ExitChanged. With -d nothing is written but the exit code is the same, so that
+tgcom --ensure STATE -d tells whether the files are already in the desired state */
+func EnsureFlags(cmd *cobra.Command) {
+	want := utils.BlockState(EnsureState)
+	labels := cmd.Flags().Changed("start-label") && cmd.Flags().Changed("end-label")
+
+	files := strings.Split(FileToRead, ",")
+	names := make([]string, len(files))
+	selections := make([]utils.Selection, len(files))
+	for i, file := range files {
+		selection := utils.Selection{Lines: LineToRead, Key: KeyToRead}
+		if name, lines, ok := strings.Cut(file, ":"); ok {
+			file, selection.Lines = name, lines
+		}
+		if labels {
+			selection.StartLabel, selection.EndLabel = StartLabel, EndLabel
+		}
+		if selection.IsEmpty() {
+			exitWithError(usageErrorf("Not specified what you want to modify: add -l flag, -k flag or -s and -e flags"))
+		}
+		if err := checkLines(file, selection.Lines); err != nil {
+			exitWithError(err)
+		}
+		names[i], selections[i] = file, selection
+	}
+
+	var errs []error
+	changed := false
+	for i, file := range names {
+		if skipIgnored(file) {
+			continue
+		}
+		n, err := utils.EnsureFileSelection(file, selections[i], want, DryRun)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", file, err))
+		} else {
+			changed = printEnsured(file, n, want) || changed
+		}
+	}
+	exitWithErrors(errs, len(files))
+	if changed {
+		os.Exit(ExitChanged)
+	}
+}
+
+/* prints how many lines of file were brought in the state want, or with -d how many would be. Returns true if some
+line was (or would be) modified */
+func printEnsured(file string, n int, want utils.BlockState) bool {
+	switch {
+	case n == 0:
+		fmt.Printf("%s: already in desired state (%s)\n", file, want)
+		return false
+	case DryRun:
+		fmt.Printf("%s: %d lines would be %s\n", file, n, want)
+	default:
+		fmt.Printf("%s: %d lines %s\n", file, n, want)
+	}
+	return true
+}
+
+/* with --git-diff the lines added or modified with respect to a git revision (or in the diff read from stdin) are
+modified with -a action, or brought in the state passed to --ensure. If -f is given only those files are modified */
+func GitDiffFlags(cmd *cobra.Command) {
+	var changes []utils.FileChanges
+	var err error
+	if GitDiffRef == "-" {
+		changes, err = utils.ParseDiff(os.Stdin)
 	} else {
-		if cmd.Flags().Changed("line"){
-			utils.ChangeFileLine(FileToRead, LineToRead, ActionToDo, DryRun)
-		} else if cmd.Flags().Changed("start-label") && cmd.Flags().Changed("end-label"){
-			utils.ChangeFileLabel(FileToRead, StartLabel, EndLabel, ActionToDo, DryRun)
+		changes, err = utils.GitDiffChanges(GitDiffRef)
+	}
+	if err != nil {
+		exitWithError(err)
+	}
+
+	if cmd.Flags().Changed("file") {
+		var selected []utils.FileChanges
+		for _, c := range changes {
+			for _, file := range strings.Split(FileToRead, ",") {
+				if filepath.Clean(file) == filepath.Clean(c.File) {
+					selected = append(selected, c)
+				}
+			}
+		}
+		changes = selected
+	}
+	var kept []utils.FileChanges
+	for _, c := range changes {
+		if !utils.IsIgnored(c.File) {
+			kept = append(kept, c)
+		}
+	}
+	changes = kept
+	if len(changes) == 0 {
+		slog.Info("no changed lines")
+		return
+	}
+
+	if !cmd.Flags().Changed("ensure") {
+		_, errs := utils.ChangeDiffLines(changes, utils.Action(ActionToDo), DryRun)
+		exitWithErrors(errs, len(changes))
+		return
+	}
+
+	want := utils.BlockState(EnsureState)
+	var errs []error
+	changed := false
+	for _, c := range changes {
+		n, err := utils.EnsureFileSelection(c.File, utils.Selection{Lines: c.Lines()}, want, DryRun)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.File, err))
 		} else {
-			log.Fatalf("Not specified what you want to modify: add -l flag or -s and -e flags")
+			changed = printEnsured(c.File, n, want) || changed
 		}
-		
+	}
+	exitWithErrors(errs, len(changes))
+	if changed {
+		os.Exit(ExitChanged)
 	}
 }
 
@@ -135,19 +350,30 @@ func customHelpFunc(cmd *cobra.Command, args []string) {
     fmt.Println("Help Message for Tgcom application")
     fmt.Println()
     fmt.Println("Usage:")
-    fmt.Println("  tgcom [-f][single file or multiple files with lines] [-l][single line or range of lines] [-d][dry run]")
-    fmt.Println()
-    fmt.Println("Available Commands:")
-    for _, c := range cmd.Commands() {
-        fmt.Printf("  %s - %s\n", c.Name(), c.Short)
+    if cmd == rootCmd {
+        fmt.Println("  tgcom [-f][single file or multiple files with lines] [-l][single line or range of lines] [-d][dry run]")
+        fmt.Println("  tgcom comment|uncomment|toggle FILE[:LINES]... [-l lines | -s start -e end | -k key] [-d]")
+    } else {
+        fmt.Printf("  %s\n", cmd.UseLine())
+        fmt.Println()
+        fmt.Println(cmd.Long)
     }
     fmt.Println()
+    if cmd.HasAvailableSubCommands() {
+        fmt.Println("Available Commands:")
+        for _, c := range cmd.Commands() {
+            fmt.Printf("  %s - %s\n", c.Name(), c.Short)
+        }
+        fmt.Println()
+    }
     fmt.Println("Flags:")
     cmd.Flags().VisitAll(func(flag *pflag.Flag) {
         fmt.Printf("  --%s: %s\n", flag.Name, flag.Usage)
     })
     fmt.Println()
-    fmt.Println("Use 'appname [command] --help' for more information about a command.")
+    fmt.Println(exitCodesHelp)
+    fmt.Println()
+    fmt.Println("Use 'tgcom [command] --help' for more information about a command.")
 }
 
 func customUsageFunc(cmd *cobra.Command) error {
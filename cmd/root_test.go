@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/ManudL2000/tgcom-cobra/utils"
+	"github.com/spf13/cobra"
+)
+
+/* "tgcom -d true -f FILE" is the form used by test/auto_test.sh: the value of -d must not be taken for a subcommand */
+func TestRootFindsLegacyDryRun(t *testing.T) {
+	cmd, _, err := rootCmd.Find([]string{"-d", "true", "-f", "prova1.go", "-l", "3-5"})
+	if err != nil || cmd != rootCmd {
+		t.Errorf("Find gives %v, %v, want the root command", cmd.Name(), err)
+	}
+}
+
+func TestRootArgs(t *testing.T) {
+	tests := []struct {
+		flags   []string
+		wantErr bool
+	}{
+		{[]string{"-f", "a.go"}, false},
+		{[]string{"-d", "true", "-f", "a.go"}, false},
+		{[]string{"-d", "false", "-f", "a.go"}, false},
+		{[]string{"-d", "-f", "a.go", "commnet"}, true},
+		{[]string{"-f", "a.go", "true"}, true},
+		{[]string{"-d", "true", "false"}, true},
+	}
+	for _, test := range tests {
+		var dryRun bool
+		var file string
+		cmd := &cobra.Command{Use: "tgcom"}
+		cmd.Flags().BoolVarP(&dryRun, "dry-run", "d", false, "")
+		cmd.Flags().StringVarP(&file, "file", "f", "", "")
+		if err := cmd.ParseFlags(test.flags); err != nil {
+			t.Fatal(err)
+		}
+		if err := rootArgs(cmd, cmd.Flags().Args()); (err != nil) != test.wantErr {
+			t.Errorf("rootArgs with %q gives %v, want error %v", test.flags, err, test.wantErr)
+		}
+	}
+}
+
+/* the comment, uncomment and toggle commands are the action themselves, -a and --ensure would contradict them */
+func TestActionCommandsHaveNoActionFlags(t *testing.T) {
+	for _, c := range []*cobra.Command{commentCmd, uncommentCmd, toggleCmd} {
+		for _, name := range []string{"action", "ensure"} {
+			if c.Flags().Lookup(name) != nil || c.InheritedFlags().Lookup(name) != nil {
+				t.Errorf("tgcom %s has the flag --%s", c.Name(), name)
+			}
+		}
+	}
+}
+
+func TestCheckSelectionFlags(t *testing.T) {
+	defer func(lines string) { LineToRead = lines }(LineToRead)
+	LineToRead = ""
+	cmd := &cobra.Command{Use: "tgcom"}
+	cmd.Flags().StringVarP(&LineToRead, "line", "l", "", "")
+	if err := checkSelectionFlags(cmd, "a.go:1-x", false); err == nil {
+		t.Error("invalid lines of a.go:1-x accepted")
+	}
+	if err := checkSelectionFlags(cmd, "a.go", false); exitCode(err) != ExitUsage {
+		t.Errorf("a.go without a selection gives %v, want a usage error", err)
+	}
+	if err := checkSelectionFlags(cmd, "a.go:1-2", false); err != nil {
+		t.Errorf("a.go:1-2 gives %v", err)
+	}
+	if err := checkSelectionFlags(cmd, "a.go", true); err != nil {
+		t.Errorf("a.go with labels gives %v", err)
+	}
+}
+
+/* with -d --ensure writes nothing, so it must say what would change and not that the lines were modified */
+func TestPrintEnsuredDryRun(t *testing.T) {
+	defer func(dryRun bool, stdout *os.File) { DryRun, os.Stdout = dryRun, stdout }(DryRun, os.Stdout)
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+	DryRun = true
+	changed := printEnsured("a.go", 2, utils.Commented)
+	unchanged := printEnsured("b.go", 0, utils.Commented)
+	w.Close()
+	out, _ := io.ReadAll(r)
+	if !changed || unchanged {
+		t.Errorf("printEnsured gives %v and %v, want true and false", changed, unchanged)
+	}
+	if want := "a.go: 2 lines would be commented\nb.go: already in desired state (commented)\n"; string(out) != want {
+		t.Errorf("output %q, want %q", out, want)
+	}
+}
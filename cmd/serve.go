@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/ManudL2000/tgcom-cobra/utils"
+	"github.com/spf13/cobra"
+)
+
+/* In these variables we store the arguments passed to the flags of tgcom serve */
+var ServeAddr string
+var ServeRoot string
+var ServeToken string
+
+/* serveCmd is the command tgcom serve, that exposes the blocks and the operations of tgcom through an HTTP/JSON API */
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "serve an HTTP/JSON API to list, preview and apply toggles",
+	Long: `serve listens on --addr and exposes the files under --root (the current directory by default):
+
+  GET  /v1/blocks?path=DIR   the blocks of the files under DIR, like tgcom status --format json
+  POST /v1/preview           the operations in the body, as JSON manifest, with a diff of every file
+  POST /v1/apply             the operations in the body are executed, like tgcom apply --format json
+
+Paths are relative to the root and cannot leave it. With --token (or the TGCOM_SERVE_TOKEN variable)
+every request must have the header "Authorization: Bearer TOKEN". The POST requests must have the content
+type application/json, and requests whose Host or Origin header is not the address of the server are
+rejected, so that web pages cannot use the API. Example:
+
+  curl -X POST localhost:8080/v1/apply -H 'Content-Type: application/json' -d '{"operations": [{"files": ["main.go"], "lines": "3", "action": "comment"}]}'`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if ServeToken == "" {
+			ServeToken = os.Getenv("TGCOM_SERVE_TOKEN")
+		}
+		server, err := utils.NewServer(ServeRoot, ServeToken)
+		if err != nil {
+			exitWithError(err)
+		}
+		server.Addr = ServeAddr
+		if host, _, err := net.SplitHostPort(ServeAddr); err == nil && ServeToken == "" {
+			if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+				slog.Warn("the server is reachable from other machines and no token is set", "addr", ServeAddr)
+			}
+		}
+
+		fmt.Printf("serving %s on http://%s\n", server.Root, ServeAddr)
+		// the timeouts keep slow or idle clients from holding connections forever
+		httpServer := &http.Server{
+			Addr:              ServeAddr,
+			Handler:           server,
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			WriteTimeout:      time.Minute,
+			IdleTimeout:       2 * time.Minute,
+		}
+		if err := httpServer.ListenAndServe(); err != nil {
+			exitWithError(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().StringVar(&ServeAddr, "addr", "127.0.0.1:8080", "pass the address to listen on")
+	serveCmd.Flags().StringVar(&ServeRoot, "root", ".", "pass the directory whose files can be read and modified")
+	serveCmd.Flags().StringVar(&ServeToken, "token", "", "pass a token that the requests must send as \"Authorization: Bearer TOKEN\"")
+}
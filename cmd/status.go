@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/ManudL2000/tgcom-cobra/utils"
+	"github.com/spf13/cobra"
+)
+
+/* In this variable we store the argument passed to the flag --expect of tgcom status */
+var ExpectedState string
+
+/* statusCmd is the command tgcom status: it finds the labelled blocks in files and directories and tells whether each
+one is commented, uncommented or mixed */
+var statusCmd = &cobra.Command{
+	Use:   "status [FILE|DIR]...",
+	Short: "report whether every labelled block is commented or not",
+	Long: `status scans the given files and directories (the current directory by default) and reports the
+state of every block delimited by "tgcom:start NAME" and "tgcom:end NAME", or by the labels given with
+-s and -e. With --expect commented (or uncommented) it exits with an error if any block is in another state.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			args = []string{"."}
+		}
+		if ExpectedState != "" && ExpectedState != string(utils.Commented) && ExpectedState != string(utils.Uncommented) {
+			exitWithError(usageErrorf("invalid state %q: use commented or uncommented", ExpectedState))
+		}
+		if cmd.Flags().Changed("start-label") != cmd.Flags().Changed("end-label") {
+			exitWithError(usageErrorf("give both -s and -e flags or none of them"))
+		}
+
+		blocks, errs := utils.ScanBlocks(args, StartLabel, EndLabel)
+		for _, err := range errs {
+			printError(err)
+		}
+
+		switch ReportFormat {
+		case "json":
+			if blocks == nil {
+				blocks = []utils.Block{}
+			}
+			out, _ := json.MarshalIndent(blocks, "", "  ")
+			fmt.Println(string(out))
+		default:
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "BLOCK\tSTATE\tLOCATION")
+			for _, block := range blocks {
+				fmt.Fprintf(w, "%s\t%s\t%s:%d-%d\n", block.Name, block.State, block.File, block.Start, block.End)
+			}
+			w.Flush()
+		}
+
+		if len(errs) > 0 {
+			os.Exit(exitCode(errs[0]))
+		}
+		if ExpectedState != "" {
+			for _, block := range blocks {
+				if string(block.State) != ExpectedState {
+					os.Exit(ExitCheckFailed)
+				}
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+	statusCmd.Flags().StringVar(&ExpectedState, "expect", "", "pass commented or uncommented to fail if a block is in another state")
+	statusCmd.Flags().StringVar(&ReportFormat, "format", "table", "pass table or json to choose the format of the report")
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ManudL2000/tgcom-cobra/utils"
+	"github.com/spf13/cobra"
+)
+
+/* In these variables we store the arguments passed to the flags of tgcom watch */
+var WatchDisable bool
+var WatchPoll bool
+var WatchInterval time.Duration
+var WatchDebounce time.Duration
+
+/* watchCmd is the command tgcom watch, that keeps a profile enabled (or disabled) while its files change */
+var watchCmd = &cobra.Command{
+	Use:   "watch PROFILE",
+	Short: "keep a profile enabled (or disabled) while its files change",
+	Long: `watch enables a profile declared in .tgcom.yaml (or disables it with --disable) and sets it again
+every time one of its files is written, e.g. by a code generator that drops the toggles. Changes are
+detected with inotify on Linux and by polling elsewhere (or with --poll); a burst of changes is applied
+once, after --debounce without new changes. Every block that is modified is logged. Stop it with Ctrl-C.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		// the blocks modified are the output of watch, so they are logged also without -v
+		setupLoggingLevel(slog.LevelInfo)
+		report := func(results []utils.ProfileResult, err error) {
+			for _, result := range results {
+				if result.Changed {
+					utils.Logger.Info("block set", "file", result.File, "block", result.Block, "state", result.State)
+				}
+			}
+			if err != nil {
+				utils.Logger.Error("profile not set", "error", err)
+			}
+		}
+
+		stop := make(chan struct{})
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-signals
+			close(stop)
+		}()
+
+		state := "enabled"
+		if WatchDisable {
+			state = "disabled"
+		}
+		utils.Logger.Info("watching profile, press Ctrl-C to stop", "profile", args[0], "state", state)
+		options := utils.WatchOptions{Poll: WatchPoll, Interval: WatchInterval, Debounce: WatchDebounce}
+		if err := utils.WatchProfile(Settings, ".", args[0], !WatchDisable, options, stop, report); err != nil {
+			exitWithError(err)
+		}
+		utils.Logger.Info("stopped", "profile", args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+	watchCmd.Flags().BoolVar(&WatchDisable, "disable", false, "keep the profile disabled instead of enabled")
+	watchCmd.Flags().BoolVar(&WatchPoll, "poll", false, "poll the files instead of using the notifications of the system")
+	watchCmd.Flags().DurationVar(&WatchInterval, "interval", time.Second, "interval between two checks of the files with --poll")
+	watchCmd.Flags().DurationVar(&WatchDebounce, "debounce", 300*time.Millisecond, "time without changes to wait before setting the profile again")
+}
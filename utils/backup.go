@@ -0,0 +1,198 @@
+package utils
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/* BackupPolicy tells whether the engine makes a backup of a file before writing it, and what happens to the backup
+once the file has been written:
+  - none: there is no backup, the file is still replaced by a temporary file in one step
+  - temp: the backup is removed (the default)
+  - keep: the backup is kept as NAME.tgcom.bak, replacing the one of the previous write
+  - numbered: every backup is kept, as NAME.tgcom.bak.1, NAME.tgcom.bak.2 and so on
+
+The backups are next to the file, or under the BackupDir of the engine in the same path as the file (e.g. the backup
+of /src/main.go is BACKUPDIR/src/main.go.tgcom.bak). Until the file has been written the backup has a unique temporary
+name, so that tgcom never overwrites a file of the user such as main.go.bak */
+type BackupPolicy string
+
+const (
+	BackupNone     BackupPolicy = "none"
+	BackupTemp     BackupPolicy = "temp"
+	BackupKeep     BackupPolicy = "keep"
+	BackupNumbered BackupPolicy = "numbered"
+)
+
+/* returns the backup policy called name */
+func ParseBackupPolicy(name string) (BackupPolicy, error) {
+	policy := BackupPolicy(name)
+	switch policy {
+	case BackupNone, BackupTemp, BackupKeep, BackupNumbered:
+		return policy, nil
+	}
+	return "", usageErrorf("invalid backup policy %q: use none, temp, keep or numbered", name)
+}
+
+/* suffixes of the temporary files: the new content of a file and its backup before it has been written */
+const (
+	tempSuffix       = ".tgcom-tmp"
+	tempBackupSuffix = ".tgcom-bak"
+)
+
+/* suffix of the backups kept with BackupKeep and BackupNumbered */
+const backupSuffix = ".tgcom.bak"
+
+/* returns the pattern of the names of the temporary files of filename for CreateTemp, e.g. .main.go.*.tgcom-tmp: they
+are hidden and can be found again after a crash (see removeStale) */
+func tempPattern(filename string, suffix string) string {
+	return "." + filepath.Base(filename) + ".*" + suffix
+}
+
+/* returns the directory of the backups of filename */
+func (e *Engine) backupDir(filename string) (string, error) {
+	if e.BackupDir == "" {
+		return filepath.Dir(filename), nil
+	}
+	dir, err := filepath.Abs(filepath.Dir(filename))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(e.BackupDir, dir[len(filepath.VolumeName(dir)):]), nil
+}
+
+/* copies the file to a temporary backup and returns its name, or an empty name with BackupNone */
+func (e *Engine) createBackup(filename string) (string, error) {
+	if e.Backup == BackupNone {
+		return "", nil
+	}
+	data, err := fs.ReadFile(e.FS, filename)
+	if err != nil {
+		return "", err
+	}
+	dir, err := e.backupDir(filename)
+	if err != nil {
+		return "", err
+	}
+	if err := e.FS.MkdirAll(dir); err != nil {
+		return "", err
+	}
+	backupFile, backupFilename, err := e.FS.CreateTemp(dir, tempPattern(filename, tempBackupSuffix))
+	if err != nil {
+		return "", err
+	}
+	if _, err := backupFile.Write(data); err != nil {
+		backupFile.Close()
+		e.FS.Remove(backupFilename)
+		return "", err
+	}
+	err = backupFile.Close()
+	if err == nil {
+		err = e.copyMode(filename, backupFilename)
+	}
+	if err != nil {
+		e.FS.Remove(backupFilename)
+		return "", err
+	}
+	return backupFilename, nil
+}
+
+/* removes the temporary backup, if there is one */
+func (e *Engine) removeBackup(backupFilename string) {
+	if backupFilename != "" {
+		e.FS.Remove(backupFilename)
+	}
+}
+
+/* once the file has been written, removes its temporary backup or gives it its final name */
+func (e *Engine) finishBackup(filename string, backupFilename string) error {
+	if backupFilename == "" {
+		return nil
+	}
+	var name string
+	switch e.Backup {
+	case BackupKeep:
+		name = filepath.Join(filepath.Dir(backupFilename), filepath.Base(filename)+backupSuffix)
+	case BackupNumbered:
+		n, err := e.lastBackupNumber(filename, filepath.Dir(backupFilename))
+		if err != nil {
+			return err
+		}
+		name = filepath.Join(filepath.Dir(backupFilename), fmt.Sprintf("%s%s.%d", filepath.Base(filename), backupSuffix, n+1))
+	default:
+		return e.FS.Remove(backupFilename)
+	}
+	if err := e.FS.Rename(backupFilename, name); err != nil {
+		return err
+	}
+	Logger.Info("backup kept", "file", filename, "backup", name)
+	return nil
+}
+
+/* returns the highest number of the numbered backups of filename in dir, 0 if there are none */
+func (e *Engine) lastBackupNumber(filename string, dir string) (int, error) {
+	prefix := filepath.Join(dir, filepath.Base(filename)+backupSuffix+".")
+	matches, err := fs.Glob(e.FS, globEscape(prefix)+"*")
+	if err != nil {
+		return 0, err
+	}
+	last := 0
+	for _, match := range matches {
+		if n, err := strconv.Atoi(strings.TrimPrefix(filepath.FromSlash(match), prefix)); err == nil && n > last {
+			last = n
+		}
+	}
+	return last, nil
+}
+
+/* a temporary file is left by a run that has been killed only if it has not been modified for staleAge: a run writes
+its temporary files in a moment, so the recent ones belong to another tgcom writing the same file at the same time */
+var staleAge = 10 * time.Minute
+
+/* returns true if the file has not been modified for staleAge */
+func (e *Engine) isStale(name string) bool {
+	info, err := fs.Stat(e.FS, name)
+	return err == nil && time.Since(info.ModTime()) >= staleAge
+}
+
+/* removes the temporary files of filename and the temporary backups left by a previous run that has been killed
+before finishing. Only the names made by tempPattern are removed, where the random part is made of digits, so that
+the temporary files of other files (e.g. .main.go.orig.1.tgcom-tmp) are left alone, and only when they are stale, so
+that the ones of another run are left alone too. The NAME.bak and NAME.tmp files left by the versions of tgcom that
+used fixed names are never removed: they cannot be told apart from the files of the user with the same names */
+func (e *Engine) removeStale(filename string) {
+	dirs := map[string]string{tempSuffix: filepath.Dir(filename)}
+	if dir, err := e.backupDir(filename); err == nil {
+		dirs[tempBackupSuffix] = dir
+	}
+	for suffix, dir := range dirs {
+		prefix := filepath.Join(dir, "."+filepath.Base(filename)+".")
+		matches, err := fs.Glob(e.FS, globEscape(prefix)+"*"+suffix)
+		if err != nil {
+			continue
+		}
+		for _, match := range matches {
+			random := strings.TrimSuffix(strings.TrimPrefix(filepath.FromSlash(match), prefix), suffix)
+			if !digitsRegexp.MatchString(random) || !e.isStale(match) {
+				continue
+			}
+			if err := e.FS.Remove(match); err == nil {
+				Logger.Info("stale temporary file removed", "file", filename, "temp", match)
+			}
+		}
+	}
+}
+
+var digitsRegexp = regexp.MustCompile(`^[0-9]+$`)
+
+/* escapes the characters that have a meaning in the patterns of filepath.Match */
+func globEscape(name string) string {
+	return globReplacer.Replace(name)
+}
+
+var globReplacer = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`)
@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+/*
+	runs a toggle of the first line of main.go on a MemFS with the given files, with the backup policy and directory,
+
+and returns the names of the files left
+*/
+func runBackup(t *testing.T, files map[string]string, policy BackupPolicy, dir string, times int) *MemFS {
+	t.Helper()
+	memfs := NewMemFS(files)
+	engine := &Engine{FS: memfs, Output: io.Discard, Backup: policy, BackupDir: dir}
+	for i := 0; i < times; i++ {
+		if _, err := engine.ChangeFileSelection("main.go", Selection{Lines: "1"}, ActionToggle, "", false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return memfs
+}
+
+func TestBackupPolicies(t *testing.T) {
+	files := map[string]string{"main.go": "x := 1\n", "main.go.bak": "mine\n"}
+	tests := []struct {
+		policy BackupPolicy
+		times  int
+		want   []string
+	}{
+		{BackupTemp, 2, []string{"main.go", "main.go.bak"}},
+		{BackupNone, 2, []string{"main.go", "main.go.bak"}},
+		{BackupKeep, 2, []string{"main.go", "main.go.bak", "main.go.tgcom.bak"}},
+		{BackupNumbered, 3, []string{"main.go", "main.go.bak", "main.go.tgcom.bak.1", "main.go.tgcom.bak.2", "main.go.tgcom.bak.3"}},
+	}
+	for _, test := range tests {
+		t.Run(string(test.policy), func(t *testing.T) {
+			memfs := runBackup(t, files, test.policy, "", test.times)
+			if names := memfs.Names(); !reflect.DeepEqual(names, test.want) {
+				t.Errorf("files %v, want %v", names, test.want)
+			}
+			if data, _ := memfs.ReadFile("main.go.bak"); string(data) != "mine\n" {
+				t.Errorf("main.go.bak of the user overwritten with %q", data)
+			}
+		})
+	}
+}
+
+func TestNumberedBackupContent(t *testing.T) {
+	memfs := runBackup(t, map[string]string{"main.go": "x := 1\n"}, BackupNumbered, "", 2)
+	for name, want := range map[string]string{"main.go.tgcom.bak.1": "x := 1\n", "main.go.tgcom.bak.2": "// x := 1\n", "main.go": "x := 1\n"} {
+		if data, _ := memfs.ReadFile(name); string(data) != want {
+			t.Errorf("%s is %q, want %q", name, data, want)
+		}
+	}
+}
+
+func TestBackupDir(t *testing.T) {
+	dir, err := (&Engine{BackupDir: "backups"}).backupDir("/src/main.go")
+	if err != nil || filepath.ToSlash(dir) != "backups/src" {
+		t.Errorf("backupDir gives %q, %v, want backups/src", dir, err)
+	}
+}
+
+func TestStaleTemporaryFilesRemoved(t *testing.T) {
+	defer func(age time.Duration) { staleAge = age }(staleAge)
+	staleAge = 0
+	files := map[string]string{
+		"main.go":                      "x := 1\n",
+		".main.go.123.tgcom-tmp":       "half written",
+		".main.go.456.tgcom-bak":       "x := 1\n",
+		".main.go.orig.1.tgcom-tmp":    "temporary file of main.go.orig",
+		".main.go.notdigits.tgcom-tmp": "not made by tgcom",
+	}
+	memfs := runBackup(t, files, BackupTemp, "", 1)
+	want := []string{".main.go.notdigits.tgcom-tmp", ".main.go.orig.1.tgcom-tmp", "main.go"}
+	if names := memfs.Names(); !reflect.DeepEqual(names, want) {
+		t.Errorf("files %v, want %v", names, want)
+	}
+}
+
+func TestRecentTemporaryFilesKept(t *testing.T) {
+	// the temporary files of another run that is writing main.go at the same time
+	files := map[string]string{
+		"main.go":                "x := 1\n",
+		".main.go.123.tgcom-tmp": "half written",
+		".main.go.456.tgcom-bak": "x := 1\n",
+		"main.go.bak":            "x := 1\n",
+	}
+	memfs := runBackup(t, files, BackupTemp, "", 1)
+	want := []string{".main.go.123.tgcom-tmp", ".main.go.456.tgcom-bak", "main.go", "main.go.bak"}
+	if names := memfs.Names(); !reflect.DeepEqual(names, want) {
+		t.Errorf("files %v, want %v", names, want)
+	}
+}
+
+/* NAME.bak and NAME.tmp may be files of the user, even when they are old copies of the file */
+func TestUserFilesKept(t *testing.T) {
+	defer func(age time.Duration) { staleAge = age }(staleAge)
+	staleAge = 0
+	files := map[string]string{
+		"main.go":     "x := 1\n",
+		"main.go.bak": "x := 1\n",
+		"main.go.tmp": "x := 1\n",
+	}
+	memfs := runBackup(t, files, BackupTemp, "", 1)
+	want := []string{"main.go", "main.go.bak", "main.go.tmp"}
+	if names := memfs.Names(); !reflect.DeepEqual(names, want) {
+		t.Errorf("files %v, want %v", names, want)
+	}
+}
+
+func TestParseBackupPolicy(t *testing.T) {
+	for _, name := range []string{"none", "temp", "keep", "numbered"} {
+		if policy, err := ParseBackupPolicy(name); err != nil || string(policy) != name {
+			t.Errorf("ParseBackupPolicy(%q) = %q, %v", name, policy, err)
+		}
+	}
+	if _, err := ParseBackupPolicy("always"); err == nil {
+		t.Error("ParseBackupPolicy(\"always\") gives no error")
+	}
+}
@@ -0,0 +1,183 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+
+	"gopkg.in/yaml.v3"
+)
+
+/* Check validates files without modifying them: labels must have their pair, blocks cannot be nested or overlap, the
+blocks named in the policy must be in the state it requires (e.g. debug blocks commented on the main branch) and the
+files referenced by a manifest must be supported */
+
+/* rules reported in the findings of Check */
+const (
+	RuleUnmatchedLabel  = "unmatched-label"
+	RuleNestedBlock     = "nested-block"
+	RulePolicy          = "policy"
+	RuleUnsupportedFile = "unsupported-file"
+)
+
+/* Finding is a problem found by Check in a file */
+type Finding struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+/* Policy tells in which state some blocks must be. The rules are enforced only on the protected branches (patterns
+like "release/*"), or on every branch if there are none. In the project configuration it is the policy section:
+
+	policy:
+	  protected-branches: ["main", "release/*"]
+	  rules:
+	    - blocks: ["debug*"]
+	      state: commented */
+type Policy struct {
+	ProtectedBranches []string     `yaml:"protected-branches"`
+	Rules             []PolicyRule `yaml:"rules"`
+}
+
+/* PolicyRule requires the blocks whose name matches one of Blocks to be in State */
+type PolicyRule struct {
+	Blocks []string   `yaml:"blocks"`
+	State  BlockState `yaml:"state"`
+}
+
+/* SourceFile is the content of a file to check. It can come from the working tree or from somewhere else, e.g. the
+git index */
+type SourceFile struct {
+	Path  string
+	Lines []string
+}
+
+/* reads a policy file, that contains the policy section of the project configuration at the top level */
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var policy Policy
+	if err := yaml.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %v", path, err)
+	}
+	return &policy, nil
+}
+
+/* returns true if the rules of the policy must be enforced on branch */
+func (p *Policy) Protects(branch string) bool {
+	if len(p.ProtectedBranches) == 0 {
+		return true
+	}
+	for _, pattern := range p.ProtectedBranches {
+		if ok, _ := path.Match(pattern, branch); ok {
+			return true
+		}
+	}
+	return false
+}
+
+/* returns the state required by the policy for the block called name, or an empty string */
+func (p *Policy) requiredState(name string) BlockState {
+	for _, rule := range p.Rules {
+		for _, pattern := range rule.Blocks {
+			if ok, _ := path.Match(pattern, name); ok {
+				return rule.State
+			}
+		}
+	}
+	return ""
+}
+
+/* checks the labels and the blocks of the sources. policy can be nil, branch is the branch on which the sources are
+going to be committed or merged */
+func CheckSources(sources []SourceFile, policy *Policy, branch string) []Finding {
+	enforce := policy != nil && policy.Protects(branch)
+	var findings []Finding
+	for _, source := range sources {
+		blocks, errs := FindBlocks(source.Path, source.Lines, "", "")
+		for _, err := range errs {
+			var labelErr *LabelError
+			if errors.As(err, &labelErr) {
+				findings = append(findings, Finding{File: labelErr.File, Line: labelErr.Line, Rule: RuleUnmatchedLabel, Message: labelErr.Message})
+			} else {
+				findings = append(findings, Finding{File: source.Path, Rule: RuleUnsupportedFile, Message: err.Error()})
+			}
+		}
+
+		// blocks are sorted by start line: a block starting before the end of a previous one is inside it or overlaps it
+		for i := range blocks {
+			for j := 0; j < i; j++ {
+				if blocks[i].Start > blocks[j].End {
+					continue
+				}
+				kind := "overlaps"
+				if blocks[i].End < blocks[j].End {
+					kind = "is nested in"
+				}
+				findings = append(findings, Finding{File: source.Path, Line: blocks[i].Start, Rule: RuleNestedBlock,
+					Message: fmt.Sprintf("block %q %s block %q (lines %d-%d)", blocks[i].Name, kind, blocks[j].Name, blocks[j].Start, blocks[j].End)})
+			}
+		}
+
+		if !enforce {
+			continue
+		}
+		for _, block := range blocks {
+			if want := policy.requiredState(block.Name); want != "" && block.State != want {
+				message := fmt.Sprintf("block %q is %s but must be %s", block.Name, block.State, want)
+				if branch != "" {
+					message += " on branch " + branch
+				}
+				findings = append(findings, Finding{File: source.Path, Line: block.Start, Rule: RulePolicy, Message: message})
+			}
+		}
+	}
+	return findings
+}
+
+/* checks that every file referenced by the operations of the manifest is supported, either by its extension or by the
+language of the operation. baseDir is the directory of the manifest */
+func CheckManifest(manifest *Manifest, baseDir string) []Finding {
+	var findings []Finding
+	for _, op := range manifest.Operations {
+		for _, pattern := range op.Files {
+			files, err := ExpandGlob(baseDir, pattern)
+			if err != nil {
+				findings = append(findings, Finding{File: pattern, Rule: RuleUnsupportedFile, Message: fmt.Sprintf("%s: %v", op.Name, err)})
+				continue
+			}
+			for _, file := range files {
+				if op.Language != "" {
+					if _, err := LanguageCommentChars(op.Language); err != nil {
+						findings = append(findings, Finding{File: file, Rule: RuleUnsupportedFile, Message: fmt.Sprintf("%s: %v", op.Name, err)})
+					}
+					continue
+				}
+				if _, err := CommentCharsFor(file); err != nil {
+					findings = append(findings, Finding{File: file, Rule: RuleUnsupportedFile, Message: fmt.Sprintf("%s: %v", op.Name, err)})
+				}
+			}
+		}
+	}
+	return findings
+}
+
+/* reads the files from the working tree, so that they can be checked with CheckSources */
+func ReadSources(files []string) ([]SourceFile, []error) {
+	var sources []SourceFile
+	var errs []error
+	for _, file := range files {
+		lines, err := readFileLines(file)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		sources = append(sources, SourceFile{Path: file, Lines: lines})
+	}
+	return sources, errs
+}
@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+/* These functions give the suggestions of the shell completion: the files that tgcom can modify and the labels that
+can be given to -s and -e */
+
+/* returns the extensions (without the dot) of the files supported by tgcom, sorted */
+func SupportedExtensions() []string {
+	extensions := []string{"ipynb"}
+	for extension := range extensionLanguages {
+		extensions = append(extensions, strings.TrimPrefix(extension, "."))
+	}
+	sort.Strings(extensions)
+	return extensions
+}
+
+/* returns the start labels found in the lines of a file if start is true, the end labels otherwise, without
+duplicates: first the labels of the named blocks (see BlockLabels), then the other commented lines that pair up as
+start and end label (see labelPairs). Commented code is never suggested, since it has no such pair */
+func FileLabels(filename string, lines []string, start bool) ([]string, error) {
+	chars, err := lineCommentChars(filename, lines)
+	if err != nil {
+		return nil, err
+	}
+	var bodies []string // the text of the commented lines that are not labels of named blocks
+	var named []string
+	for i, lineContent := range lines {
+		if chars[i] == "" {
+			continue
+		}
+		_, body, _, ok := commentedBody(lineContent, chars[i])
+		body = strings.TrimSpace(body)
+		if !ok || body == "" {
+			continue
+		}
+		startName := startLabelRegexp.FindStringSubmatch(body)
+		endName := endLabelRegexp.FindStringSubmatch(body)
+		switch {
+		case startName != nil || endName != nil:
+			if (startName != nil) == start {
+				named = append(named, body)
+			}
+		case looksLikeLabel(body):
+			bodies = append(bodies, body)
+		}
+	}
+
+	seen := map[string]bool{}
+	var labels []string
+	for _, label := range append(named, labelPairs(bodies, start)...) {
+		if !seen[label] {
+			seen[label] = true
+			labels = append(labels, label)
+		}
+	}
+	return labels, nil
+}
+
+/* the words of a start label and the words of the end labels that close it */
+var labelEndWords = map[string][]string{
+	"start": {"end", "stop"},
+	"begin": {"end"},
+	"open":  {"close"},
+}
+
+var labelWordRegexp = regexp.MustCompile(`[A-Za-z]+`)
+
+/* returns the start labels (the end labels if start is false) of the pairs found in labels, in order. A label pairs
+with a following one that is equal but for a start word changed into its end word (see labelEndWords), ignoring case:
+"debug-start" and "debug-end", "BEGIN MOCK" and "END MOCK" */
+func labelPairs(labels []string, start bool) []string {
+	var paired []string
+	for i, label := range labels {
+		for _, loc := range labelWordRegexp.FindAllStringIndex(label, -1) {
+			for _, endWord := range labelEndWords[strings.ToLower(label[loc[0]:loc[1]])] {
+				endLabel := label[:loc[0]] + endWord + label[loc[1]:]
+				for _, other := range labels[i+1:] {
+					if strings.EqualFold(other, endLabel) {
+						if start {
+							paired = append(paired, label)
+						} else {
+							paired = append(paired, other)
+						}
+					}
+				}
+			}
+		}
+	}
+	return paired
+}
+
+/* a label is a short comment without the characters of code, like "debug-start" or "BEGIN MOCK" */
+func looksLikeLabel(text string) bool {
+	return len(text) <= 40 && len(strings.Fields(text)) <= 4 && !strings.ContainsAny(text, "(){}[];=<>\"'`,")
+}
@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFileLabels(t *testing.T) {
+	lines := []string{
+		"#!/bin/sh",
+		"# tgcom:start mock",
+		"# echo 1",
+		"# tgcom:end mock",
+		"# debug-start",
+		"# set -x",
+		"# debug-end",
+		"# BEGIN MOCK",
+		"# echo 2",
+		"# end mock",
+		"# start server",
+		"# cleanup",
+		"# open",
+		"echo done",
+	}
+	tests := []struct {
+		start bool
+		want  []string
+	}{
+		{true, []string{"tgcom:start mock", "debug-start", "BEGIN MOCK"}},
+		{false, []string{"tgcom:end mock", "debug-end", "end mock"}},
+	}
+	for _, test := range tests {
+		got, err := FileLabels("script.sh", lines, test.start)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("FileLabels(start %v) = %q, want %q", test.start, got, test.want)
+		}
+	}
+}
+
+func TestLabelPairs(t *testing.T) {
+	tests := []struct {
+		labels []string
+		start  bool
+		want   []string
+	}{
+		{[]string{"debug-start", "debug-stop"}, true, []string{"debug-start"}},
+		{[]string{"Start Mock", "END MOCK"}, false, []string{"END MOCK"}},
+		{[]string{"open db", "close db"}, false, []string{"close db"}},
+		// the end label must follow the start label
+		{[]string{"debug-end", "debug-start"}, true, nil},
+		// "restart" is not the word "start"
+		{[]string{"restart", "reend"}, true, nil},
+		{[]string{"echo 1", "echo 2"}, true, nil},
+	}
+	for _, test := range tests {
+		if got := labelPairs(test.labels, test.start); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("labelPairs(%q, %v) = %q, want %q", test.labels, test.start, got, test.want)
+		}
+	}
+}
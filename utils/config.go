@@ -0,0 +1,352 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+/* The configuration of tgcom is made of layers, each one overriding the previous: the defaults, the user configuration
+(config.yaml in the tgcom directory of the user configuration directory, e.g. ~/.config/tgcom/config.yaml), the
+project configuration (the first .tgcom.yaml found going up from the current directory) and the TGCOM_* environment
+variables. The flags of the command line are applied last, by the commands. A configuration file looks like:
+
+	action: uncomment
+	labels:
+	  start: region
+	  end: endregion
+	languages:
+	  .tpl: GoLang
+	backup: numbered
+	backup-dir: .tgcom/backups
+	ignore: ["vendor", "*.pb.go"]
+	profiles: ...
+	policy: ...
+
+Languages, profiles and ignore patterns of the layers are added together, a profile with the same name as one of a
+previous layer replaces it */
+
+/* name of the project configuration file */
+const ConfigFilename = ".tgcom.yaml"
+
+/* Config is the content of a configuration file */
+type Config struct {
+	Action    Action                    `yaml:"action,omitempty"`
+	Labels    LabelSyntax               `yaml:"labels,omitempty"`
+	Languages map[string]string         `yaml:"languages,omitempty"`
+	Backup    BackupPolicy              `yaml:"backup,omitempty"`
+	BackupDir string                    `yaml:"backup-dir,omitempty"`
+	Ignore    []string                  `yaml:"ignore,omitempty"`
+	Profiles  map[string][]ProfileBlock `yaml:"profiles,omitempty"`
+	Policy    *Policy                   `yaml:"policy,omitempty"`
+}
+
+/* LabelSyntax are the words that start and end the labels of the named blocks (see BlockLabels) */
+type LabelSyntax struct {
+	Start string `yaml:"start,omitempty"`
+	End   string `yaml:"end,omitempty"`
+}
+
+/* returns the configuration used when there are no configuration files */
+func DefaultConfig() *Config {
+	return &Config{
+		Action: ActionToggle,
+		Labels: LabelSyntax{Start: "tgcom:start", End: "tgcom:end"},
+		Backup: BackupTemp,
+	}
+}
+
+/* reads a configuration file */
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid configuration %s: %v", path, err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration %s: %w", path, err)
+	}
+	return &config, nil
+}
+
+/* returns the path of the project configuration file, looking for it in dir and in its parents. The path is relative
+if dir is relative (e.g. ../.tgcom.yaml), or an error if there is none */
+func FindConfig(dir string) (string, error) {
+	current := dir
+	for {
+		path := filepath.Join(current, ConfigFilename)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+		abs, err := filepath.Abs(current)
+		if err != nil || filepath.Dir(abs) == abs {
+			return "", fmt.Errorf("no %s found in %s or in its parents", ConfigFilename, dir)
+		}
+		current = filepath.Join(current, "..")
+	}
+}
+
+/* returns the path of the user configuration file, that may not exist */
+func UserConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tgcom", "config.yaml"), nil
+}
+
+/* returns the configuration given by the TGCOM_* variables of the environment, read with lookup (e.g. os.LookupEnv):
+TGCOM_ACTION, TGCOM_LABEL_START, TGCOM_LABEL_END, TGCOM_BACKUP, TGCOM_BACKUP_DIR, TGCOM_IGNORE (patterns separated by commas) and
+TGCOM_LANGUAGES (e.g. ".tpl=GoLang,.inc=Bash") */
+func EnvConfig(lookup func(string) (string, bool)) (*Config, error) {
+	var config Config
+	if value, ok := lookup("TGCOM_ACTION"); ok {
+		config.Action = Action(value)
+	}
+	if value, ok := lookup("TGCOM_LABEL_START"); ok {
+		config.Labels.Start = value
+	}
+	if value, ok := lookup("TGCOM_LABEL_END"); ok {
+		config.Labels.End = value
+	}
+	if value, ok := lookup("TGCOM_BACKUP"); ok {
+		config.Backup = BackupPolicy(value)
+	}
+	if value, ok := lookup("TGCOM_BACKUP_DIR"); ok {
+		config.BackupDir = value
+	}
+	if value, ok := lookup("TGCOM_IGNORE"); ok && value != "" {
+		config.Ignore = strings.Split(value, ",")
+	}
+	if value, ok := lookup("TGCOM_LANGUAGES"); ok && value != "" {
+		config.Languages = map[string]string{}
+		for _, pair := range strings.Split(value, ",") {
+			extension, language, ok := strings.Cut(pair, "=")
+			if !ok {
+				return nil, usageErrorf("invalid TGCOM_LANGUAGES %q: use EXTENSION=LANGUAGE pairs separated by commas", value)
+			}
+			config.Languages[extension] = language
+		}
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid environment: %w", err)
+	}
+	return &config, nil
+}
+
+/* returns the effective configuration: the defaults, the user configuration, the project configuration at
+projectPath (or the one found with FindConfig if it is empty) and the environment. The files that have been read are
+returned too. The patterns of the profiles, the ignore patterns and the backup directory are made relative to the current
+directory, so that they can be expanded with ExpandGlob(".", pattern) */
+func LoadLayeredConfig(projectPath string, lookup func(string) (string, bool)) (*Config, []string, error) {
+	config := DefaultConfig()
+	var sources []string
+	load := func(path string) error {
+		layer, err := LoadConfig(path)
+		if err != nil {
+			return err
+		}
+		layer.rebase(filepath.Dir(path))
+		config.Merge(layer)
+		sources = append(sources, path)
+		return nil
+	}
+
+	if userPath, err := UserConfigPath(); err == nil {
+		if _, err := os.Stat(userPath); err == nil {
+			if err := load(userPath); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if projectPath == "" {
+		if value, ok := lookup("TGCOM_CONFIG"); ok {
+			projectPath = value
+		}
+	}
+	if projectPath == "" {
+		projectPath, _ = FindConfig(".")
+	}
+	if projectPath != "" {
+		if err := load(projectPath); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	env, err := EnvConfig(lookup)
+	if err != nil {
+		return nil, nil, err
+	}
+	config.Merge(env)
+	return config, sources, nil
+}
+
+/* overrides the values of config with the ones set in other */
+func (config *Config) Merge(other *Config) {
+	if other.Action != "" {
+		config.Action = other.Action
+	}
+	if other.Labels.Start != "" {
+		config.Labels.Start = other.Labels.Start
+	}
+	if other.Labels.End != "" {
+		config.Labels.End = other.Labels.End
+	}
+	if other.Backup != "" {
+		config.Backup = other.Backup
+	}
+	if other.BackupDir != "" {
+		config.BackupDir = other.BackupDir
+	}
+	config.Ignore = append(config.Ignore, other.Ignore...)
+	for extension, language := range other.Languages {
+		if config.Languages == nil {
+			config.Languages = map[string]string{}
+		}
+		config.Languages[extension] = language
+	}
+	for name, blocks := range other.Profiles {
+		if config.Profiles == nil {
+			config.Profiles = map[string][]ProfileBlock{}
+		}
+		config.Profiles[name] = blocks
+	}
+	if other.Policy != nil {
+		config.Policy = other.Policy
+	}
+}
+
+/* makes the relative patterns of the profiles, the ignore patterns with slashes and the backup directory relative to
+dir, the directory of the configuration file. The ignore patterns without slashes and the ones starting with "**"
+match in any directory, so they are left as they are */
+func (config *Config) rebase(dir string) {
+	if config.BackupDir != "" && !filepath.IsAbs(config.BackupDir) {
+		config.BackupDir = filepath.Join(dir, config.BackupDir)
+	}
+	for i, pattern := range config.Ignore {
+		if strings.Contains(pattern, "/") && !strings.HasPrefix(pattern, "**/") && !filepath.IsAbs(pattern) {
+			config.Ignore[i] = filepath.Join(dir, pattern)
+		}
+	}
+	for _, blocks := range config.Profiles {
+		for i := range blocks {
+			for j, pattern := range blocks[i].Files {
+				if !filepath.IsAbs(pattern) {
+					blocks[i].Files[j] = filepath.Join(dir, pattern)
+				}
+			}
+		}
+	}
+}
+
+/* returns an error if a value of the configuration is not valid */
+func (config *Config) validate() error {
+	if config.Action != "" {
+		if err := checkAction(config.Action); err != nil {
+			return err
+		}
+	}
+	if config.Backup != "" {
+		if _, err := ParseBackupPolicy(string(config.Backup)); err != nil {
+			return err
+		}
+	}
+	for extension, language := range config.Languages {
+		if !strings.HasPrefix(extension, ".") {
+			return usageErrorf("invalid extension %q: it must start with a dot", extension)
+		}
+		if _, err := ParseLanguage(language); err != nil {
+			return err
+		}
+	}
+	for _, pattern := range config.Ignore {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return usageErrorf("invalid ignore pattern %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+/* makes the configuration effective for the functions of the package: the block labels, the languages of the
+extensions, the backup policy and directory of DefaultEngine and the ignored files */
+func (config *Config) Apply() {
+	SetBlockLabels(config.Labels.Start, config.Labels.End)
+	extensions := make([]string, 0, len(config.Languages))
+	for extension := range config.Languages {
+		extensions = append(extensions, extension)
+	}
+	sort.Strings(extensions)
+	for _, extension := range extensions {
+		language, _ := ParseLanguage(config.Languages[extension])
+		extensionLanguages[extension] = language
+	}
+	DefaultEngine.Backup = config.Backup
+	DefaultEngine.BackupDir = config.BackupDir
+	IgnorePatterns = config.Ignore
+}
+
+/* patterns of the files that tgcom must not modify. A pattern without slashes matches the name of the file or of one
+of its directories (e.g. "vendor" or "*.pb.go"), a pattern starting with a "**" directory matches in any directory and
+the other ones match the whole path, relative to the current directory (e.g. "internal/gen/*.go", where "**" matches
+any number of directories). The patterns of the configuration files are relative to their directory, see rebase */
+var IgnorePatterns []string
+
+/* returns true if the file matches one of IgnorePatterns */
+func IsIgnored(filename string) bool {
+	parts := strings.Split(filepath.ToSlash(filepath.Clean(filename)), "/")
+	var absParts []string // parts of the absolute path of the file, computed for the first pattern that needs them
+	for _, pattern := range IgnorePatterns {
+		if !strings.Contains(pattern, "/") {
+			for _, part := range parts {
+				if ok, _ := filepath.Match(pattern, part); ok {
+					return true
+				}
+			}
+			continue
+		}
+		if strings.HasPrefix(pattern, "**/") {
+			patternParts := strings.Split(filepath.ToSlash(filepath.Clean(pattern)), "/")
+			if ok, _ := matchParts(patternParts, parts); ok {
+				return true
+			}
+			continue
+		}
+		// the file and the pattern are compared as absolute paths, so that ../gen/a.go matches the pattern gen/*.go
+		// of ../.tgcom.yaml, rebased to ../gen/*.go
+		absPattern, err := filepath.Abs(pattern)
+		if err != nil {
+			continue
+		}
+		if absParts == nil {
+			abs, err := filepath.Abs(filename)
+			if err != nil {
+				continue
+			}
+			absParts = strings.Split(filepath.ToSlash(abs), "/")
+		}
+		if ok, _ := matchParts(strings.Split(filepath.ToSlash(absPattern), "/"), absParts); ok {
+			return true
+		}
+	}
+	return false
+}
+
+/* returns the files that are not ignored */
+func withoutIgnored(files []string) []string {
+	var kept []string
+	for _, file := range files {
+		if IsIgnored(file) {
+			Logger.Info("file ignored", "file", file)
+			continue
+		}
+		kept = append(kept, file)
+	}
+	return kept
+}
@@ -0,0 +1,203 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+/* writes a configuration file, creating its directory */
+func writeConfig(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+/* returns a lookup function for LoadLayeredConfig that reads the variables from env */
+func envLookup(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+}
+
+/* points the user configuration directory to a temporary directory, and returns the path of the user configuration */
+func tempUserConfig(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("AppData", dir)
+	path, err := UserConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadLayeredConfig(t *testing.T) {
+	userPath := tempUserConfig(t)
+	writeConfig(t, userPath, `action: uncomment
+labels:
+  start: region
+backup: keep
+ignore: ["vendor"]
+profiles:
+  debug:
+    - files: ["user.go"]
+      block: user
+  trace:
+    - files: ["trace.go"]
+      block: trace
+`)
+	projectDir := t.TempDir()
+	projectPath := filepath.Join(projectDir, ConfigFilename)
+	writeConfig(t, projectPath, `action: comment
+languages:
+  .tpl: GoLang
+backup-dir: backups
+ignore: ["gen/*.go"]
+profiles:
+  debug:
+    - files: ["src/*.go"]
+      block: debug
+`)
+	env := map[string]string{"TGCOM_BACKUP": "numbered", "TGCOM_IGNORE": "*.tmp", "TGCOM_LABEL_END": "endregion"}
+
+	config, sources, err := LoadLayeredConfig(projectPath, envLookup(env))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{userPath, projectPath}; !reflect.DeepEqual(sources, want) {
+		t.Errorf("sources %q, want %q", sources, want)
+	}
+	want := &Config{
+		Action:    ActionComment,
+		Labels:    LabelSyntax{Start: "region", End: "endregion"},
+		Languages: map[string]string{".tpl": "GoLang"},
+		Backup:    BackupNumbered,
+		BackupDir: filepath.Join(projectDir, "backups"),
+		Ignore:    []string{"vendor", filepath.Join(projectDir, "gen/*.go"), "*.tmp"},
+		Profiles: map[string][]ProfileBlock{
+			"debug": {{Files: []string{filepath.Join(projectDir, "src/*.go")}, Block: "debug"}},
+			"trace": {{Files: []string{filepath.Join(filepath.Dir(userPath), "trace.go")}, Block: "trace"}},
+		},
+	}
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("config\n%+v\nwant\n%+v", config, want)
+	}
+}
+
+func TestLoadLayeredConfigDefaults(t *testing.T) {
+	tempUserConfig(t)
+	config, sources, err := LoadLayeredConfig("", envLookup(map[string]string{"TGCOM_CONFIG": filepath.Join(t.TempDir(), "none.yaml")}))
+	if err == nil {
+		t.Fatalf("no error for a missing TGCOM_CONFIG file: %+v, %q", config, sources)
+	}
+
+	projectPath := filepath.Join(t.TempDir(), ConfigFilename)
+	writeConfig(t, projectPath, "")
+	config, sources, err = LoadLayeredConfig("", envLookup(map[string]string{"TGCOM_CONFIG": projectPath}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(config, DefaultConfig()) || !reflect.DeepEqual(sources, []string{projectPath}) {
+		t.Errorf("config %+v from %q, want the defaults from %s", config, sources, projectPath)
+	}
+}
+
+func TestLoadLayeredConfigErrors(t *testing.T) {
+	tempUserConfig(t)
+	projectPath := filepath.Join(t.TempDir(), ConfigFilename)
+	writeConfig(t, projectPath, "action: delete\n")
+	if _, _, err := LoadLayeredConfig(projectPath, envLookup(nil)); err == nil || !strings.Contains(err.Error(), projectPath) {
+		t.Errorf("invalid project configuration gives %v, want an error about %s", err, projectPath)
+	}
+
+	writeConfig(t, projectPath, "")
+	for _, env := range []map[string]string{
+		{"TGCOM_ACTION": "delete"},
+		{"TGCOM_BACKUP": "always"},
+		{"TGCOM_LANGUAGES": ".tpl"},
+		{"TGCOM_LANGUAGES": "tpl=GoLang"},
+		{"TGCOM_IGNORE": "[a"},
+	} {
+		if _, _, err := LoadLayeredConfig(projectPath, envLookup(env)); err == nil {
+			t.Errorf("no error for the environment %v", env)
+		}
+	}
+}
+
+
+func TestRebaseIgnore(t *testing.T) {
+	config := &Config{Ignore: []string{"vendor", "*.pb.go", "gen/*.go", "**/tmp/*", "/abs/*.go"}}
+	config.rebase("..")
+	want := []string{"vendor", "*.pb.go", "../gen/*.go", "**/tmp/*", "/abs/*.go"}
+	if !reflect.DeepEqual(config.Ignore, want) {
+		t.Errorf("rebased patterns %q, want %q", config.Ignore, want)
+	}
+}
+
+func TestIsIgnored(t *testing.T) {
+	defer func(patterns []string) { IgnorePatterns = patterns }(IgnorePatterns)
+	// the patterns of a configuration file in the parent directory, once rebased
+	IgnorePatterns = []string{"vendor", "*.pb.go", "../gen/*.go", "**/tmp/*"}
+
+	abs, err := filepath.Abs("../gen/a.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		file string
+		want bool
+	}{
+		{"vendor/lib/a.go", true},
+		{"src/vendor/a.go", true},
+		{"api.pb.go", true},
+		{"../gen/a.go", true},
+		{"./../gen/a.go", true},
+		{abs, true},
+		{"../gen/sub/a.go", false},
+		{"gen/a.go", false},
+		{"tmp/a", true},
+		{"x/y/tmp/a", true},
+		{"x/tmp/y/a", false},
+		{"main.go", false},
+	}
+	for _, test := range tests {
+		if got := IsIgnored(test.file); got != test.want {
+			t.Errorf("IsIgnored(%q) = %v, want %v", test.file, got, test.want)
+		}
+	}
+}
+
+/* tgcom status and tgcom check read the files with CollectFiles, that must skip the ignored ones like the commands
+that modify files */
+func TestCollectFilesSkipsIgnored(t *testing.T) {
+	defer func(patterns []string) { IgnorePatterns = patterns }(IgnorePatterns)
+	dir := t.TempDir()
+	for _, name := range []string{"main.go", "api.pb.go", "vendor/lib/lib.go", "src/vendor/x.go", "src/app.go"} {
+		writeConfig(t, filepath.Join(dir, name), "package x\n// tgcom:start debug\nx := 1\n// tgcom:end debug\n")
+	}
+	IgnorePatterns = []string{"vendor", "*.pb.go"}
+
+	files, errs := CollectFiles([]string{dir, filepath.Join(dir, "api.pb.go")})
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	want := []string{filepath.Join(dir, "main.go"), filepath.Join(dir, "src", "app.go")}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("CollectFiles gives %q, want %q", files, want)
+	}
+
+	blocks, _ := ScanBlocks([]string{dir}, "", "")
+	if len(blocks) != 2 {
+		t.Errorf("ScanBlocks gives %d blocks, want the 2 of the files not ignored: %+v", len(blocks), blocks)
+	}
+}
@@ -0,0 +1,241 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/* With --git-diff only the lines added or modified with respect to a git revision are changed. The changed lines are
+taken from a unified diff, produced by git or read from the standard input, and turned in a list of ranges for each
+file, that is selected with the Lines field of Selection (e.g. "3-5,8") */
+
+/* FileChanges is the list of ranges of lines added or modified in a file */
+type FileChanges struct {
+	File   string
+	Ranges [][2]int
+}
+
+/* returns the ranges of the file in the syntax of Selection.Lines */
+func (c FileChanges) Lines() string {
+	var parts []string
+	for _, r := range c.Ranges {
+		parts = append(parts, fmt.Sprintf("%d-%d", r[0], r[1]))
+	}
+	return strings.Join(parts, ",")
+}
+
+/* returns the lines changed in the working tree with respect to ref. Paths are relative to the current directory and
+only the files inside it are considered. A ref starting with "-" is rejected, git would take it for an option (e.g.
+--output=FILE, that writes a file) */
+func GitDiffChanges(ref string) ([]FileChanges, error) {
+	if strings.HasPrefix(ref, "-") {
+		return nil, usageErrorf("invalid git revision %q: it cannot start with \"-\"", ref)
+	}
+	out, err := gitOutput("diff", "--relative", "--unified=0", "--no-color", "--no-ext-diff", "--diff-filter=ACMR",
+		"--src-prefix=a/", "--dst-prefix=b/", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+	return ParseDiff(strings.NewReader(out))
+}
+
+/* parses a unified diff (like the output of git diff) and returns the lines added in the new version of every file.
+The b/ prefix is removed from the paths of the files whose "diff --git" header uses the a/ and b/ prefixes, so that the
+paths of diffs made with --no-prefix or with diff -u are kept as they are; deleted files are skipped */
+func ParseDiff(r io.Reader) ([]FileChanges, error) {
+	var changes []FileChanges
+	var current *FileChanges
+	newLine, remaining := 0, 0
+	lineNumber := 0
+	gitPrefix := false // the header of the current file is "diff --git a/... b/..."
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lineNumber++
+		lineContent := scanner.Text()
+
+		// inside a hunk, count the lines of the new version until it ends
+		if remaining > 0 && current != nil {
+			switch {
+			case strings.HasPrefix(lineContent, "+"):
+				addLine(current, newLine)
+				newLine++
+				remaining--
+			case strings.HasPrefix(lineContent, " "), lineContent == "":
+				newLine++
+				remaining--
+			}
+			// removed lines and "\ No newline at end of file" are not in the new version
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(lineContent, "diff --git "):
+			header := strings.TrimPrefix(lineContent, "diff --git ")
+			gitPrefix = strings.HasPrefix(header, "a/") || strings.HasPrefix(header, `"a/`)
+		case strings.HasPrefix(lineContent, "+++ "):
+			file, err := diffPath(strings.TrimPrefix(lineContent, "+++ "), gitPrefix)
+			if err != nil {
+				return nil, fmt.Errorf("diff line %d: %v", lineNumber, err)
+			}
+			current, gitPrefix = nil, false
+			if file == "/dev/null" {
+				continue
+			}
+			changes = append(changes, FileChanges{File: file})
+			current = &changes[len(changes)-1]
+		case strings.HasPrefix(lineContent, "@@ "):
+			if current == nil {
+				continue
+			}
+			start, count, err := parseHunkHeader(lineContent)
+			if err != nil {
+				return nil, fmt.Errorf("diff line %d: %v", lineNumber, err)
+			}
+			newLine, remaining = start, count
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// files with only removed lines have nothing to change
+	var result []FileChanges
+	for _, c := range changes {
+		if len(c.Ranges) > 0 {
+			result = append(result, c)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].File < result[j].File })
+	return result, nil
+}
+
+/* adds line to the ranges of changes, extending the last range if it is the next line */
+func addLine(changes *FileChanges, line int) {
+	if n := len(changes.Ranges); n > 0 && changes.Ranges[n-1][1] == line-1 {
+		changes.Ranges[n-1][1] = line
+		return
+	}
+	changes.Ranges = append(changes.Ranges, [2]int{line, line})
+}
+
+/* returns the first line and the number of lines of the new version from a header like "@@ -10,2 +12,3 @@ func" */
+func parseHunkHeader(header string) (int, int, error) {
+	fields := strings.Fields(header)
+	if len(fields) < 3 || !strings.HasPrefix(fields[2], "+") {
+		return 0, 0, fmt.Errorf("invalid hunk header %q", header)
+	}
+	startStr, countStr, found := strings.Cut(strings.TrimPrefix(fields[2], "+"), ",")
+	start, err := strconv.Atoi(startStr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid hunk header %q", header)
+	}
+	count := 1
+	if found {
+		if count, err = strconv.Atoi(countStr); err != nil {
+			return 0, 0, fmt.Errorf("invalid hunk header %q", header)
+		}
+	}
+	return start, count, nil
+}
+
+/* returns the path of a "+++" line of the diff, removing the quotes git uses for unusual names and, if gitPrefix is
+true, the b/ prefix */
+func diffPath(field string, gitPrefix bool) (string, error) {
+	// a tab separates the path from the timestamp in the diffs made by diff -u
+	field, _, _ = strings.Cut(field, "\t")
+	if strings.HasPrefix(field, `"`) {
+		unquoted, err := strconv.Unquote(field)
+		if err != nil {
+			return "", fmt.Errorf("invalid path %s", field)
+		}
+		field = unquoted
+	}
+	if field == "/dev/null" || !gitPrefix {
+		return field, nil
+	}
+	return strings.TrimPrefix(field, "b/"), nil
+}
+
+/* applies action to the lines of changes. Files that cannot be changed are reported in the returned errors and do not
+stop the other ones */
+func ChangeDiffLines(changes []FileChanges, action Action, dryrun bool) (int, []error) {
+	total := 0
+	var errs []error
+	for _, c := range changes {
+		if _, err := os.Stat(c.File); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if _, err := CommentCharsFor(c.File); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		n, err := ChangeFileSelection(c.File, Selection{Lines: c.Lines()}, action, "", dryrun)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.File, err))
+		}
+		total += n
+	}
+	return total, errs
+}
+
+/* returns a unified diff between the lines of a file before and after a change. tgcom replaces lines without adding
+or removing them, so the two versions have the same number of lines and the diff only needs to find the lines that
+differ and show them with 3 lines of context */
+func UnifiedDiff(filename string, oldLines []string, newLines []string) string {
+	const context = 3
+	var b strings.Builder
+	for i := 0; i < len(oldLines); {
+		if oldLines[i] == newLines[i] {
+			i++
+			continue
+		}
+		// extend the hunk while the next change is near enough to share the context
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for j := i; j < len(oldLines) && j <= end+2*context; j++ {
+			if oldLines[j] != newLines[j] {
+				end = j
+			}
+		}
+		stop := end + context + 1
+		if stop > len(oldLines) {
+			stop = len(oldLines)
+		}
+
+		if b.Len() == 0 {
+			fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", filename, filename)
+		}
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", start+1, stop-start, start+1, stop-start)
+		for j := start; j < stop; {
+			if oldLines[j] == newLines[j] {
+				b.WriteString(" " + oldLines[j] + "\n")
+				j++
+				continue
+			}
+			k := j
+			for k < stop && oldLines[k] != newLines[k] {
+				k++
+			}
+			for _, lineContent := range oldLines[j:k] {
+				b.WriteString("-" + lineContent + "\n")
+			}
+			for _, lineContent := range newLines[j:k] {
+				b.WriteString("+" + lineContent + "\n")
+			}
+			j = k
+		}
+		i = stop
+	}
+	return b.String()
+}
@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		diff string
+		want []FileChanges
+	}{
+		{"modified", `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -2,3 +2,4 @@ func main() {
+ 	a := 1
+-	b := 2
++	b := 3
++	c := 4
+ 	return
+@@ -10 +11 @@
+-x
++y
+`, []FileChanges{{File: "main.go", Ranges: [][2]int{{3, 4}, {11, 11}}}}},
+		{"new file", `diff --git a/new.sh b/new.sh
+new file mode 100644
+--- /dev/null
++++ b/new.sh
+@@ -0,0 +1 @@
++echo hi
+`, []FileChanges{{File: "new.sh", Ranges: [][2]int{{1, 1}}}}},
+		{"deleted file", `diff --git a/old.sh b/old.sh
+deleted file mode 100644
+--- a/old.sh
++++ /dev/null
+@@ -1,2 +0,0 @@
+-echo 1
+-echo 2
+`, nil},
+		{"only removed lines", `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -3,2 +2,0 @@
+-a
+-b
+`, nil},
+		{"rename", `diff --git a/old.go b/dir/new.go
+similarity index 90%
+rename from old.go
+rename to dir/new.go
+--- a/old.go
++++ b/dir/new.go
+@@ -5,0 +6,2 @@
++x
++y
+`, []FileChanges{{File: "dir/new.go", Ranges: [][2]int{{6, 7}}}}},
+		{"no prefix", `diff --git b/main.go b/main.go
+--- b/main.go
++++ b/main.go
+@@ -1 +1 @@
+-x
++y
+`, []FileChanges{{File: "b/main.go", Ranges: [][2]int{{1, 1}}}}},
+		{"diff -u", "--- b/main.go\t2024-06-12 10:00:00\n+++ b/main.go\t2024-06-12 10:01:00\n@@ -1,2 +1,2 @@\n-x\n+y\n z\n",
+			[]FileChanges{{File: "b/main.go", Ranges: [][2]int{{1, 1}}}}},
+		{"quoted path and missing newline", `diff --git "a/my file.py" "b/my file.py"
+--- "a/my file.py"
++++ "b/my file.py"
+@@ -1 +1 @@
+-x
+\ No newline at end of file
++y
+\ No newline at end of file
+`, []FileChanges{{File: "my file.py", Ranges: [][2]int{{1, 1}}}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseDiff(strings.NewReader(test.diff))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ParseDiff = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseHunkHeader(t *testing.T) {
+	tests := []struct {
+		header       string
+		start, count int
+		ok           bool
+	}{
+		{"@@ -10,2 +12,3 @@ func f()", 12, 3, true},
+		{"@@ -0,0 +1 @@", 1, 1, true},
+		{"@@ -3,2 +2,0 @@", 2, 0, true},
+		{"@@ -1 @@", 0, 0, false},
+		{"@@ -1 +x,2 @@", 0, 0, false},
+	}
+	for _, test := range tests {
+		start, count, err := parseHunkHeader(test.header)
+		if (err == nil) != test.ok || start != test.start || count != test.count {
+			t.Errorf("parseHunkHeader(%q) = %d, %d, %v", test.header, start, count, err)
+		}
+	}
+}
+
+func TestFileChangesLines(t *testing.T) {
+	c := FileChanges{File: "a.go", Ranges: [][2]int{{1, 1}, {3, 5}}}
+	if got := c.Lines(); got != "1-1,3-5" {
+		t.Errorf("Lines() = %q", got)
+	}
+}
+
+/* the ref is passed to git diff, that must not take it for an option */
+func TestGitDiffChangesRejectsOptions(t *testing.T) {
+	for _, ref := range []string{"--output=/tmp/x", "-p", "--"} {
+		_, err := GitDiffChanges(ref)
+		var usage *UsageError
+		if !errors.As(err, &usage) {
+			t.Errorf("GitDiffChanges(%q) gives %v, want a usage error", ref, err)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+/* Some files contain pieces of code written in other languages: an html page can have javascript inside <script> and
+css inside <style>, .vue and .svelte components are made of a template, a script and a style section and a php file is
+html with php code between <?php and ?>. For these files the comment characters depend on the region in which the
+selected lines are, so the functions of this file find the language used in every line of the file */
+
+/* a region is a piece of the file that starts with the tag open and ends with the tag close. language is the key of
+CommentChars used for the lines inside the region */
+type region struct {
+	open     string
+	close    string
+	language string
+}
+
+var htmlRegions = []region{
+	{open: "<script", close: "</script", language: "JS"},
+	{open: "<style", close: "</style", language: "CSS"},
+}
+
+var phpRegions = []region{
+	{open: "<?php", close: "?>", language: "PHP"},
+	{open: "<?=", close: "?>", language: "PHP"},
+}
+
+/* files in which the language of a line depends on the region where it is */
+var embeddedExtensions = map[string]bool{
+	".html":   true,
+	".htm":    true,
+	".vue":    true,
+	".svelte": true,
+	".php":    true,
+	// code snippets of documents, see fenced.go
+	".md":       true,
+	".markdown": true,
+	".rst":      true,
+}
+
+/* returns true if the file can contain regions written in different languages */
+func IsEmbedded(filename string) bool {
+	return embeddedExtensions[filepath.Ext(filename)]
+}
+
+/* returns, for each line of the file, the key of CommentChars of the language used in that line. Lines outside of any
+region are html. A line that closes a region (e.g. "</script>" or "?>") belongs to the outer region, so that it gets
+commented with the same syntax as the tag that opened the region */
+func RegionLanguages(filename string, lines []string) []string {
+	switch filepath.Ext(filename) {
+	case ".md", ".markdown":
+		return markdownLanguages(lines)
+	case ".rst":
+		return rstLanguages(lines)
+	}
+	withPHP := filepath.Ext(filename) == ".php"
+
+	languages := make([]string, len(lines))
+	var htmlRegion *region // script or style region we are in, nil if we are in plain html
+	var phpRegion *region  // php block we are in, nil if we are not in php code
+	for i, lineContent := range lines {
+		lower := strings.ToLower(lineContent)
+		// a tag line commented by tgcom (e.g. "<!-- </script> -->") still closes the region, so that it can be
+		// uncommented with the syntax that commented it
+		trimmed := strings.TrimSpace(Uncomment(strings.TrimSpace(lower), CommentChars["HTML"]))
+
+		outer := "HTML"
+		if htmlRegion != nil {
+			outer = htmlRegion.language
+		}
+		switch {
+		case phpRegion != nil && strings.HasPrefix(trimmed, phpRegion.close):
+			languages[i] = outer
+		case phpRegion != nil:
+			languages[i] = phpRegion.language
+		case htmlRegion != nil && strings.HasPrefix(trimmed, htmlRegion.close):
+			languages[i] = "HTML"
+		default:
+			languages[i] = outer
+		}
+
+		// update the regions with the tags found in the line, in the order they appear
+		pos := 0
+		for pos < len(lower) {
+			rest := lower[pos:]
+			if phpRegion != nil {
+				idx := strings.Index(rest, phpRegion.close)
+				if idx < 0 {
+					break
+				}
+				pos += idx + len(phpRegion.close)
+				phpRegion = nil
+				continue
+			}
+
+			next, nextIdx := (*region)(nil), -1
+			candidates := []region{}
+			if withPHP {
+				candidates = append(candidates, phpRegions...)
+			}
+			if htmlRegion == nil {
+				candidates = append(candidates, htmlRegions...)
+			}
+			for j := range candidates {
+				if idx := strings.Index(rest, candidates[j].open); idx >= 0 && (nextIdx < 0 || idx < nextIdx) {
+					next, nextIdx = &candidates[j], idx
+				}
+			}
+			closeIdx := -1
+			if htmlRegion != nil {
+				closeIdx = strings.Index(rest, htmlRegion.close)
+			}
+
+			switch {
+			case closeIdx >= 0 && (nextIdx < 0 || closeIdx < nextIdx):
+				htmlRegion = nil
+				pos += closeIdx + 1
+			case next != nil && next.close == "?>":
+				phpRegion = next
+				pos += nextIdx + len(next.open)
+			case next != nil:
+				opened := *next
+				tagEnd := strings.Index(rest[nextIdx:], ">")
+				if tagEnd < 0 {
+					tagEnd = len(rest) - nextIdx
+				}
+				if opened.language == "JS" && isTypeScriptTag(rest[nextIdx:nextIdx+tagEnd]) {
+					opened.language = "TS"
+				}
+				htmlRegion = &opened
+				pos += nextIdx + len(opened.open)
+			default:
+				pos = len(lower)
+			}
+		}
+	}
+	return languages
+}
+
+/* a script tag contains typescript when it is declared with lang="ts" (vue and svelte) or with a typescript type */
+func isTypeScriptTag(tag string) bool {
+	return strings.Contains(tag, `lang="ts"`) || strings.Contains(tag, `lang='ts'`) ||
+		strings.Contains(tag, "typescript")
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+)
+
+/* The errors returned by the functions of the package belong to one of the following types, so that the command line
+can tell them apart with errors.As (e.g. to choose the exit code). The message is the one of the wrapped error; errors
+without one of these types are generic failures */
+
+/* UsageError is returned when the arguments are not valid: a bad range, action, state or regex, an unsupported
+language or file extension */
+type UsageError struct {
+	Err error
+}
+
+func (e *UsageError) Error() string { return e.Err.Error() }
+
+func (e *UsageError) Unwrap() error { return e.Err }
+
+/* NoMatchError is returned when the selection does not match the file: a line out of range, a label, key, symbol,
+cell or profile file that is not found */
+type NoMatchError struct {
+	Err error
+}
+
+func (e *NoMatchError) Error() string { return e.Err.Error() }
+
+func (e *NoMatchError) Unwrap() error { return e.Err }
+
+/* IOError is returned when a file cannot be read or written */
+type IOError struct {
+	Err error
+}
+
+func (e *IOError) Error() string { return e.Err.Error() }
+
+func (e *IOError) Unwrap() error { return e.Err }
+
+func usageErrorf(format string, args ...interface{}) error {
+	return &UsageError{Err: fmt.Errorf(format, args...)}
+}
+
+func noMatchErrorf(format string, args ...interface{}) error {
+	return &NoMatchError{Err: fmt.Errorf(format, args...)}
+}
+
+func ioErrorf(format string, args ...interface{}) error {
+	return &IOError{Err: fmt.Errorf(format, args...)}
+}
+
+/* returns err as an IOError, unless it is nil or has already one of the types above (e.g. a line out of range found
+while a file is written) */
+func ioError(err error) error {
+	if err == nil || isTyped(err) {
+		return err
+	}
+	return &IOError{Err: err}
+}
+
+func isTyped(err error) bool {
+	var usage *UsageError
+	var noMatch *NoMatchError
+	var io *IOError
+	return errors.As(err, &usage) || errors.As(err, &noMatch) || errors.As(err, &io)
+}
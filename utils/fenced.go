@@ -0,0 +1,186 @@
+package utils
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+/* Markdown and reStructuredText documents contain code snippets: in markdown they are fenced between ``` (or ~~~)
+lines whose info string tells the language of the snippet, in reStructuredText they are the indented lines after a
+".. code-block:: lang" directive. Lines inside a snippet are commented with the syntax of the snippet language, the
+other lines of the document with html comments */
+
+/* maps the language names used in info strings and code-block directives to the keys of CommentChars */
+var fenceLanguages = map[string]string{
+	"go":          "GoLang",
+	"golang":      "GoLang",
+	"js":          "JS",
+	"javascript":  "JS",
+	"jsx":         "JS",
+	"ts":          "TS",
+	"typescript":  "TS",
+	"tsx":         "TS",
+	"sh":          "Bash",
+	"bash":        "Bash",
+	"shell":       "Bash",
+	"zsh":         "Bash",
+	"console":     "Bash",
+	"c":           "C++/C",
+	"cpp":         "C++/C",
+	"c++":         "C++/C",
+	"h":           "C++/C",
+	"java":        "Java",
+	"py":          "Pyhton",
+	"python":      "Pyhton",
+	"python3":     "Pyhton",
+	"rb":          "Ruby",
+	"ruby":        "Ruby",
+	"pl":          "Perl",
+	"perl":        "Perl",
+	"php":         "PHP",
+	"swift":       "Swift",
+	"kt":          "Kotlin",
+	"kotlin":      "Kotlin",
+	"r":           "R",
+	"hs":          "Haskell",
+	"haskell":     "Haskell",
+	"sql":         "SQL",
+	"rs":          "Rust",
+	"rust":        "Rust",
+	"scala":       "Scala",
+	"dart":        "Dart",
+	"objc":        "Objective-C",
+	"objectivec":  "Objective-C",
+	"objective-c": "Objective-C",
+	"matlab":      "MATLAB",
+	"lua":         "Lua",
+	"erl":         "Erlang",
+	"erlang":      "Erlang",
+	"ex":          "Elixir",
+	"elixir":      "Elixir",
+	"vhdl":        "VHDL",
+	"verilog":     "Verilog",
+	"html":        "HTML",
+	"xml":         "HTML",
+	"css":         "CSS",
+}
+
+/* returns the key of CommentChars for the language named in an info string (e.g. "go" or "python {linenos=true}").
+Unknown or missing languages are treated as the text of the document */
+func fenceLanguage(info string) string {
+	fields := strings.Fields(strings.ToLower(info))
+	if len(fields) == 0 {
+		return "HTML"
+	}
+	name := strings.Trim(fields[0], "{}.")
+	if language, ok := fenceLanguages[name]; ok {
+		return language
+	}
+	return "HTML"
+}
+
+/* returns, for each line of a markdown document, the key of CommentChars to use in that line. The fence lines
+themselves belong to the document */
+func markdownLanguages(lines []string) []string {
+	languages := make([]string, len(lines))
+	fence := ""    // the ``` or ~~~ sequence that opened the current snippet, empty outside snippets
+	language := "" // language of the current snippet
+	for i, lineContent := range lines {
+		// a fence commented by tgcom (e.g. "<!-- ```go -->") still delimits the snippet, see documentLine
+		lineContent = documentLine(lineContent)
+		trimmed := strings.TrimLeft(lineContent, " ")
+		indented := len(lineContent)-len(trimmed) > 3
+
+		if fence == "" {
+			languages[i] = "HTML"
+			if !indented && (strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")) {
+				fence = trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, trimmed[:1]))]
+				language = fenceLanguage(trimmed[len(fence):])
+			}
+			continue
+		}
+
+		// the closing fence uses the same character of the opening one and is at least as long
+		if !indented && strings.HasPrefix(trimmed, fence) && strings.TrimSpace(strings.TrimLeft(trimmed, fence[:1])) == "" {
+			languages[i] = "HTML"
+			fence = ""
+			continue
+		}
+		languages[i] = language
+	}
+	return languages
+}
+
+/* returns, for each line of a reStructuredText document, the key of CommentChars to use in that line. The snippet of a
+code-block directive is made of the lines (and the blank lines between them) indented more than the directive; the
+directive and its options belong to the document */
+func rstLanguages(lines []string) []string {
+	languages := make([]string, len(lines))
+	inBlock := false
+	inOptions := false // lines between the directive and the snippet, like ":linenos:"
+	indent := 0        // indentation of the directive
+	language := ""
+	for i, lineContent := range lines {
+		lineContent = documentLine(lineContent)
+		trimmed := strings.TrimSpace(lineContent)
+		lineIndent := len(lineContent) - len(strings.TrimLeft(lineContent, " \t"))
+
+		if inBlock && trimmed != "" && lineIndent <= indent {
+			inBlock = false
+		}
+		if inBlock {
+			if inOptions && strings.HasPrefix(trimmed, ":") {
+				languages[i] = "HTML"
+				continue
+			}
+			if trimmed != "" {
+				inOptions = false
+			}
+			if inOptions {
+				languages[i] = "HTML"
+			} else {
+				languages[i] = language
+			}
+			continue
+		}
+
+		languages[i] = "HTML"
+		for _, directive := range []string{".. code-block::", ".. sourcecode::", ".. code::"} {
+			if strings.HasPrefix(trimmed, directive) {
+				inBlock, inOptions = true, true
+				indent = lineIndent
+				language = fenceLanguage(strings.TrimPrefix(trimmed, directive))
+				break
+			}
+		}
+	}
+
+	return languages
+}
+
+/* returns the line without the html comment that tgcom adds to the lines of the document. The fences and the
+directives are commented with it, and they must still open and close the snippets so that uncommenting them later
+uses the same syntax of the comment */
+func documentLine(lineContent string) string {
+	return Uncomment(lineContent, CommentChars["HTML"])
+}
+
+/* returns the function that applies action to a line with its comment characters. In reStructuredText the code of a
+snippet is indented under its directive, so in .rst files the comment characters go after the indentation of the
+line: at the start of the line they would end the snippet */
+func actionChange(filename string, action Action) func(string, string) string {
+	return indentedChange(filename, func(lineContent string, char string) string {
+		return applyAction(lineContent, char, action)
+	})
+}
+
+/* in .rst files change is applied to the line without its indentation, see actionChange */
+func indentedChange(filename string, change func(string, string) string) func(string, string) string {
+	if filepath.Ext(filename) != ".rst" {
+		return change
+	}
+	return func(lineContent string, char string) string {
+		indent, rest := splitIndent(lineContent)
+		return indent + change(rest, char)
+	}
+}
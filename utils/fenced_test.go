@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMarkdownLanguages(t *testing.T) {
+	lines := []string{
+		"# Title",
+		"```go",
+		"x := 1",
+		"```",
+		"text",
+		"~~~~ python {linenos=true}",
+		"print(x)",
+		"```",
+		"~~~~",
+		"    ```go",
+		"```unknown",
+		"code",
+		"```",
+	}
+	want := []string{"HTML", "HTML", "GoLang", "HTML", "HTML", "HTML", "Pyhton", "Pyhton", "HTML", "HTML", "HTML", "HTML", "HTML"}
+	if got := markdownLanguages(lines); !reflect.DeepEqual(got, want) {
+		t.Errorf("markdownLanguages = %v, want %v", got, want)
+	}
+}
+
+func TestRstLanguages(t *testing.T) {
+	lines := []string{
+		"Title",
+		".. code-block:: bash",
+		"   :linenos:",
+		"",
+		"   echo 1",
+		"",
+		"   echo 2",
+		"text",
+		"   .. code:: go",
+		"",
+		"      x := 1",
+		"   quoted text",
+	}
+	want := []string{"HTML", "HTML", "HTML", "HTML", "Bash", "Bash", "Bash", "HTML", "HTML", "HTML", "GoLang", "HTML"}
+	if got := rstLanguages(lines); !reflect.DeepEqual(got, want) {
+		t.Errorf("rstLanguages = %v, want %v", got, want)
+	}
+}
+
+/* in .rst files the comment characters go after the indentation, so that the line stays in the snippet; in the other
+files they are at the start of the line */
+func TestCommentIndentation(t *testing.T) {
+	tests := []struct {
+		filename string
+		lines    string
+		input    string
+		want     string
+	}{
+		{"doc.rst", "3-4", ".. code-block:: python\n\n   x = 1\n      y = 2\n", ".. code-block:: python\n\n   # x = 1\n      # y = 2\n"},
+		{"doc.md", "2", "```python\n    x = 1\n```\n", "```python\n#     x = 1\n```\n"},
+		{"main.py", "2", "def f():\n    return 1\n", "def f():\n#     return 1\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.filename, func(t *testing.T) {
+			memfs := NewMemFS(map[string]string{test.filename: test.input})
+			engine := &Engine{FS: memfs, Output: &strings.Builder{}}
+			if err := engine.ChangeFileLine(test.filename, test.lines, ActionComment, false); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := memfs.ReadFile(test.filename); string(got) != test.want {
+				t.Errorf("comment gives\n%s\nwant\n%s", got, test.want)
+			}
+			if err := engine.ChangeFileLine(test.filename, test.lines, ActionUncomment, false); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := memfs.ReadFile(test.filename); string(got) != test.input {
+				t.Errorf("uncomment gives\n%s\nwant\n%s", got, test.input)
+			}
+		})
+	}
+}
+
+/* the fences and directives commented by tgcom still delimit the snippets, so the lines of a snippet commented
+together with its fences are uncommented with the syntax of the snippet */
+func TestCommentedFences(t *testing.T) {
+	tests := []struct {
+		filename string
+		input    string
+		want     string
+	}{
+		{"doc.md", "text\n```go\nx := 1\n```\n", "<!-- text -->\n<!-- ```go -->\n// x := 1\n<!-- ``` -->\n"},
+		{"doc.md", "~~~python\nx = 1\n~~~\nafter\n", "<!-- ~~~python -->\n# x = 1\n<!-- ~~~ -->\n<!-- after -->\n"},
+		{"doc.rst", ".. code-block:: go\n\n   x := 1\ntext\n", "<!-- .. code-block:: go -->\n<!--  -->\n   // x := 1\n<!-- text -->\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.filename, func(t *testing.T) {
+			memfs := NewMemFS(map[string]string{test.filename: test.input})
+			engine := &Engine{FS: memfs, Output: &strings.Builder{}}
+			if err := engine.ChangeFileLine(test.filename, "1-4", ActionComment, false); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := memfs.ReadFile(test.filename); string(got) != test.want {
+				t.Errorf("comment gives\n%s\nwant\n%s", got, test.want)
+			}
+			if err := engine.ChangeFileLine(test.filename, "1-4", ActionUncomment, false); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := memfs.ReadFile(test.filename); string(got) != test.input {
+				t.Errorf("uncomment gives\n%s\nwant\n%s", got, test.input)
+			}
+		})
+	}
+}
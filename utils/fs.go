@@ -0,0 +1,228 @@
+package utils
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/* FS is the filesystem on which the engine reads and writes the files. It is an fs.FS that can also create, rename and
+remove files, which is all the engine needs to write a file safely (backup, temporary file, rename). CreateTemp
+creates a new file in dir as os.CreateTemp does, the last "*" of pattern is replaced by a random string, and returns
+its name. Globbing (see fs.Glob) is used to find the temporary files left by a run that has been killed */
+type FS interface {
+	fs.FS
+	Create(name string) (io.WriteCloser, error)
+	CreateTemp(dir string, pattern string) (io.WriteCloser, string, error)
+	MkdirAll(dir string) error
+	Rename(oldname string, newname string) error
+	Remove(name string) error
+}
+
+/* OSFS is the filesystem of the operating system. Unlike os.DirFS names are paths as accepted by the os package, so
+they can be absolute or contain ".." */
+type OSFS struct{}
+
+func (OSFS) Open(name string) (fs.File, error) { return os.Open(name) }
+
+func (OSFS) Create(name string) (io.WriteCloser, error) { return os.Create(name) }
+
+func (OSFS) CreateTemp(dir string, pattern string) (io.WriteCloser, string, error) {
+	file, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, "", err
+	}
+	return file, file.Name(), nil
+}
+
+func (OSFS) MkdirAll(dir string) error { return os.MkdirAll(dir, 0755) }
+
+/* the files created by CreateTemp can only be read by their owner, so the engine gives them the mode of the file
+they replace */
+func (OSFS) Chmod(name string, mode fs.FileMode) error { return os.Chmod(name, mode) }
+
+/* implements fs.GlobFS, with the patterns of filepath.Glob */
+func (OSFS) Glob(pattern string) ([]string, error) { return filepath.Glob(pattern) }
+
+func (OSFS) Rename(oldname string, newname string) error { return os.Rename(oldname, newname) }
+
+func (OSFS) Remove(name string) error { return os.Remove(name) }
+
+/* MemFS is a filesystem kept in memory, for tests and for programs that modify sources that are not on disk. Names are
+cleaned, so "a/../b.go" and "b.go" are the same file; there are no directories. The modification time of a file is
+the time it was last written */
+type MemFS struct {
+	mu       sync.Mutex
+	files    map[string][]byte
+	modTimes map[string]time.Time
+	temps    int
+}
+
+/* returns a MemFS with the given files, the keys are the names and the values the contents */
+func NewMemFS(files map[string]string) *MemFS {
+	m := &MemFS{files: map[string][]byte{}, modTimes: map[string]time.Time{}}
+	now := time.Now()
+	for name, content := range files {
+		m.files[memName(name)] = []byte(content)
+		m.modTimes[memName(name)] = now
+	}
+	return m
+}
+
+func memName(name string) string {
+	return path.Clean(filepath.ToSlash(name))
+}
+
+func (m *MemFS) Open(name string) (fs.File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.files[memName(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	info := memFileInfo{name: path.Base(memName(name)), size: int64(len(data)), modTime: m.modTimes[memName(name)]}
+	return &memFile{Reader: bytes.NewReader(data), info: info}, nil
+}
+
+/* returns the content of a file, it implements fs.ReadFileFS */
+func (m *MemFS) ReadFile(name string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.files[memName(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	return append([]byte(nil), data...), nil
+}
+
+/* replaces the content of a file, creating it if needed */
+func (m *MemFS) WriteFile(name string, data []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[memName(name)] = append([]byte(nil), data...)
+	m.modTimes[memName(name)] = time.Now()
+}
+
+/* the file is created empty and gets its content when the writer is closed */
+func (m *MemFS) Create(name string) (io.WriteCloser, error) {
+	m.WriteFile(name, nil)
+	return &memWriter{fs: m, name: memName(name)}, nil
+}
+
+/* the random string of the name is a counter, the names of the temporary files are unique but predictable */
+func (m *MemFS) CreateTemp(dir string, pattern string) (io.WriteCloser, string, error) {
+	m.mu.Lock()
+	var name string
+	for {
+		m.temps++
+		name = path.Join(memName(dir), pattern+strconv.Itoa(m.temps))
+		if i := strings.LastIndex(pattern, "*"); i >= 0 {
+			name = path.Join(memName(dir), pattern[:i]+strconv.Itoa(m.temps)+pattern[i+1:])
+		}
+		if _, ok := m.files[name]; !ok {
+			break
+		}
+	}
+	m.files[name] = nil
+	m.modTimes[name] = time.Now()
+	m.mu.Unlock()
+	return &memWriter{fs: m, name: name}, name, nil
+}
+
+/* there are no directories, so there is nothing to create */
+func (m *MemFS) MkdirAll(dir string) error { return nil }
+
+/* implements fs.GlobFS, the pattern is matched with the whole name of every file */
+func (m *MemFS) Glob(pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	var matches []string
+	for _, name := range m.Names() {
+		if ok, _ := path.Match(memName(pattern), name); ok {
+			matches = append(matches, name)
+		}
+	}
+	return matches, nil
+}
+
+func (m *MemFS) Rename(oldname string, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.files[memName(oldname)]
+	if !ok {
+		return &fs.PathError{Op: "rename", Path: oldname, Err: fs.ErrNotExist}
+	}
+	modTime := m.modTimes[memName(oldname)]
+	delete(m.files, memName(oldname))
+	delete(m.modTimes, memName(oldname))
+	m.files[memName(newname)] = data
+	m.modTimes[memName(newname)] = modTime
+	return nil
+}
+
+func (m *MemFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.files[memName(name)]; !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	delete(m.files, memName(name))
+	delete(m.modTimes, memName(name))
+	return nil
+}
+
+/* returns the names of the files, sorted */
+func (m *MemFS) Names() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.files))
+	for name := range m.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type memFile struct {
+	*bytes.Reader
+	info memFileInfo
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+func (f *memFile) Close() error { return nil }
+
+type memFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (i memFileInfo) Name() string       { return i.name }
+func (i memFileInfo) Size() int64        { return i.size }
+func (i memFileInfo) Mode() fs.FileMode  { return 0644 }
+func (i memFileInfo) ModTime() time.Time { return i.modTime }
+func (i memFileInfo) IsDir() bool        { return false }
+func (i memFileInfo) Sys() interface{}   { return nil }
+
+type memWriter struct {
+	fs   *MemFS
+	name string
+	buf  bytes.Buffer
+}
+
+func (w *memWriter) Write(p []byte) (int, error) { return w.buf.Write(p) }
+
+func (w *memWriter) Close() error {
+	w.fs.WriteFile(w.name, w.buf.Bytes())
+	return nil
+}
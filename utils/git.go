@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+/* runs git with args in the current directory and returns its output */
+func gitOutput(args ...string) (string, error) {
+	return gitInput(nil, args...)
+}
+
+/* same as gitOutput, but stdin is passed to git as its standard input */
+func gitInput(stdin []byte, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	command := exec.Command("git", args...)
+	if stdin != nil {
+		command.Stdin = bytes.NewReader(stdin)
+	}
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+	if err := command.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+/* returns the name of the current branch. In CI pipelines the repository is often checked out on a detached HEAD, so
+the branch is taken from the variables of GitHub Actions and GitLab CI when they are set */
+func CurrentBranch() (string, error) {
+	for _, name := range []string{"GITHUB_HEAD_REF", "GITHUB_REF_NAME", "CI_COMMIT_REF_NAME"} {
+		if branch := os.Getenv(name); branch != "" {
+			return branch, nil
+		}
+	}
+	out, err := gitOutput("rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+/* returns the path of the root of the working tree */
+func GitRoot() (string, error) {
+	out, err := gitOutput("rev-parse", "--show-toplevel")
+	return strings.TrimSpace(out), err
+}
+
+/* returns the paths (relative to the root of the working tree) of the files added, copied, modified or renamed in the
+index */
+func StagedFiles() ([]string, error) {
+	out, err := gitOutput("diff", "--cached", "--name-only", "-z", "--diff-filter=ACMR")
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, file := range strings.Split(out, "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+/* returns the content of a file in the index, not the one in the working tree */
+func ReadStaged(path string) ([]byte, error) {
+	out, err := gitOutput("cat-file", "blob", ":"+path)
+	return []byte(out), err
+}
+
+/* replaces the content of a file in the index, keeping its mode. The working tree is not modified */
+func WriteStaged(path string, content []byte) error {
+	entry, err := gitOutput("ls-files", "--stage", "--", path)
+	if err != nil {
+		return err
+	}
+	fields := strings.Fields(entry)
+	if len(fields) < 1 {
+		return fmt.Errorf("%s is not in the index", path)
+	}
+	hash, err := gitInput(content, "hash-object", "-w", "--stdin", "--path", path)
+	if err != nil {
+		return err
+	}
+	_, err = gitOutput("update-index", "--cacheinfo", fields[0]+","+strings.TrimSpace(hash)+","+path)
+	return err
+}
@@ -0,0 +1,193 @@
+package utils
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"testing"
+)
+
+/* The golden tests run every action, in line and label mode and in dry run, on an input file for every language of
+CommentChars, and compare the result with the files in testdata/golden/LANGUAGE. After a change of the output run
+	go test ./utils -update
+to rewrite the golden files, and check their diff before committing them */
+var update = flag.Bool("update", false, "rewrite the golden files with the current output")
+
+/* the lines selected in line mode and the labels used in label mode, they match the input files */
+const (
+	goldenLines      = "2-6"
+	goldenStartLabel = "tgcom-golden-start"
+	goldenEndLabel   = "tgcom-golden-end"
+)
+
+/* a golden case runs on the engine and its output is the modified file or, in dry run, what is printed */
+type goldenCase struct {
+	name   string
+	dryrun bool
+	run    func(e *Engine, filename string) error
+}
+
+func goldenCases() []goldenCase {
+	var cases []goldenCase
+	for _, action := range []Action{ActionComment, ActionUncomment, ActionToggle} {
+		action := action
+		cases = append(cases,
+			goldenCase{"line-" + string(action), false, func(e *Engine, filename string) error {
+				return e.ChangeFileLine(filename, goldenLines, action, false)
+			}},
+			goldenCase{"label-" + string(action), false, func(e *Engine, filename string) error {
+				return e.ChangeFileLabel(filename, goldenStartLabel, goldenEndLabel, action, false)
+			}},
+		)
+	}
+	return append(cases,
+		goldenCase{"line-dryrun", true, func(e *Engine, filename string) error {
+			return e.ChangeFileLine(filename, goldenLines, ActionToggle, true)
+		}},
+		goldenCase{"label-dryrun", true, func(e *Engine, filename string) error {
+			return e.ChangeFileLabel(filename, goldenStartLabel, goldenEndLabel, ActionToggle, true)
+		}},
+	)
+}
+
+/* returns the directory of the fixtures of a language, the characters that cannot be in a file name become "_" */
+func goldenDir(language string) string {
+	return filepath.Join("testdata", "golden", regexp.MustCompile(`[^A-Za-z0-9]`).ReplaceAllString(language, "_"))
+}
+
+func TestGolden(t *testing.T) {
+	var languages []string
+	for language := range CommentChars {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+
+	for _, language := range languages {
+		language := language
+		t.Run(language, func(t *testing.T) {
+			dir := goldenDir(language)
+			inputs, err := filepath.Glob(filepath.Join(dir, "input.*"))
+			if err != nil || len(inputs) != 1 {
+				t.Fatalf("%s must contain exactly one input file, found %v", dir, inputs)
+			}
+			filename := filepath.Base(inputs[0])
+			if got, err := LanguageFor(filename); err != nil || got != Language(language) {
+				t.Fatalf("%s is a %s file, want %s (%v)", filename, got, language, err)
+			}
+			runGolden(t, dir, inputs[0])
+		})
+	}
+}
+
+/* the fixtures of testdata/golden/embedded are files with regions in different languages (see embedded.go and
+fenced.go): the selected lines cross the boundaries of the regions */
+func TestGoldenEmbedded(t *testing.T) {
+	dirs, err := filepath.Glob(filepath.Join("testdata", "golden", "embedded", "*"))
+	if err != nil || len(dirs) == 0 {
+		t.Fatalf("no fixtures of embedded regions found (%v)", err)
+	}
+	for _, dir := range dirs {
+		dir := dir
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			inputs, err := filepath.Glob(filepath.Join(dir, "input.*"))
+			if err != nil || len(inputs) != 1 {
+				t.Fatalf("%s must contain exactly one input file, found %v", dir, inputs)
+			}
+			if !IsEmbedded(inputs[0]) {
+				t.Fatalf("%s is not a file with regions", inputs[0])
+			}
+			runGolden(t, dir, inputs[0])
+		})
+	}
+}
+
+/* toggling the selected lines twice, or commenting and then uncommenting them, gives back the input: the lines
+commented in a region must be recognised with the syntax that commented them, also when they are the tags or the
+fences that delimit the region */
+func TestGoldenEmbeddedRoundTrip(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "golden", "embedded", "*", "input.*"))
+	if err != nil || len(inputs) == 0 {
+		t.Fatalf("no fixtures of embedded regions found (%v)", err)
+	}
+	selections := map[string]Selection{
+		"line":  {Lines: goldenLines},
+		"label": {StartLabel: goldenStartLabel, EndLabel: goldenEndLabel},
+	}
+	for _, inputPath := range inputs {
+		input, err := os.ReadFile(inputPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		filename := filepath.Base(inputPath)
+		for mode, selection := range selections {
+			for _, actions := range [][2]Action{{ActionToggle, ActionToggle}, {ActionComment, ActionUncomment}} {
+				name := filepath.Base(filepath.Dir(inputPath)) + "/" + mode + "-" + string(actions[0]) + "-" + string(actions[1])
+				t.Run(name, func(t *testing.T) {
+					memfs := NewMemFS(map[string]string{filename: string(input)})
+					engine := &Engine{FS: memfs, Output: io.Discard}
+					for _, action := range actions {
+						if _, err := engine.ChangeFileSelection(filename, selection, action, "", false); err != nil {
+							t.Fatal(err)
+						}
+					}
+					if got, _ := memfs.ReadFile(filename); !bytes.Equal(got, input) {
+						t.Errorf("%s after %s and %s\n%s\nwant\n%s", filename, actions[0], actions[1], got, input)
+					}
+				})
+			}
+		}
+	}
+}
+
+/* runs the golden cases on the input file and compares the results with the golden files of dir */
+func runGolden(t *testing.T, dir string, inputPath string) {
+	input, err := os.ReadFile(inputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Base(inputPath)
+	for _, c := range goldenCases() {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			var output bytes.Buffer
+			memfs := NewMemFS(map[string]string{filename: string(input)})
+			if err := c.run(&Engine{FS: memfs, Output: &output}, filename); err != nil {
+				t.Fatal(err)
+			}
+			got := output.Bytes()
+			if !c.dryrun {
+				if got, err = memfs.ReadFile(filename); err != nil {
+					t.Fatal(err)
+				}
+				if names := memfs.Names(); len(names) != 1 {
+					t.Errorf("files left after the change: %v", names)
+				}
+			} else if content, _ := memfs.ReadFile(filename); !bytes.Equal(content, input) {
+				t.Errorf("dry run modified %s", filename)
+			}
+			checkGolden(t, filepath.Join(dir, c.name+".golden"), got)
+		})
+	}
+}
+
+/* compares got with the golden file, or writes it with -update */
+func checkGolden(t *testing.T, path string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test ./utils -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from the output\n--- want\n%s\n--- got\n%s", path, want, got)
+	}
+}
@@ -0,0 +1,150 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+)
+
+/* The pre-commit hook checks the content that is going to be committed, that is the content of the files in the git
+index and not the one in the working tree (a file can have changes that are not staged). With fix, the blocks that
+are not in the state required by the policy are brought in that state directly in the index, so that unstaged changes
+are never committed by mistake; the working tree is updated too when it has no unstaged changes */
+
+/* first line of the hooks installed by tgcom, used to recognise them */
+const hookMarker = "# installed by tgcom hook install"
+
+/* returns the staged content of the supported files, paths are relative to the root of the working tree */
+func StagedSources() ([]SourceFile, error) {
+	files, err := StagedFiles()
+	if err != nil {
+		return nil, err
+	}
+	var sources []SourceFile
+	for _, file := range files {
+		if _, err := CommentCharsFor(file); err != nil {
+			continue
+		}
+		content, err := ReadStaged(file)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, SourceFile{Path: file, Lines: splitLines(content)})
+	}
+	return sources, nil
+}
+
+/* splits the content of a file in lines, like readFileLines does */
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+}
+
+/* joins lines in the content of a file, like writeFileLines does */
+func joinLines(lines []string) []byte {
+	var buf bytes.Buffer
+	for _, lineContent := range lines {
+		buf.WriteString(lineContent + "\n")
+	}
+	return buf.Bytes()
+}
+
+/* brings the blocks of the staged sources that violate the policy in the state it requires, writing the new content
+in the index. Returns the policy findings that have been fixed; the sources are updated with the new content */
+func FixStaged(sources []SourceFile, policy *Policy, branch string) ([]Finding, error) {
+	if policy == nil || !policy.Protects(branch) {
+		return nil, nil
+	}
+	var fixed []Finding
+	for i, source := range sources {
+		blocks, _ := FindBlocks(source.Path, source.Lines, "", "")
+		lines := source.Lines
+		var fileFixed []Finding
+		for _, block := range blocks {
+			want := policy.requiredState(block.Name)
+			if want == "" || block.State == want || block.End-block.Start < 2 {
+				continue
+			}
+			// the lines between the two labels
+			selection := Selection{Lines: fmt.Sprintf("%d-%d", block.Start+1, block.End-1)}
+			newLines, err := EnsureLines(source.Path, lines, selection, want)
+			if err != nil {
+				return fixed, fmt.Errorf("%s: %w", source.Path, err)
+			}
+			lines = newLines
+			fileFixed = append(fileFixed, Finding{File: source.Path, Line: block.Start, Rule: RulePolicy,
+				Message: fmt.Sprintf("block %q is now %s", block.Name, want)})
+		}
+		if len(fileFixed) == 0 {
+			continue
+		}
+
+		original := joinLines(source.Lines)
+		if err := WriteStaged(source.Path, joinLines(lines)); err != nil {
+			return fixed, err
+		}
+		// update the working tree only if it has the same content of the index
+		if worktree, err := os.ReadFile(source.Path); err == nil && bytes.Equal(worktree, original) {
+			if err := writeFileLines(source.Path, lines); err != nil {
+				return fixed, err
+			}
+		}
+		sources[i].Lines = lines
+		fixed = append(fixed, fileFixed...)
+	}
+	return fixed, nil
+}
+
+/* installs a pre-commit hook that runs "tgcom hook run" with the given executable. A hook that was not installed by
+tgcom is replaced only with force. Returns the path of the hook */
+func InstallHook(executable string, fix bool, force bool) (string, error) {
+	path, err := hookPath()
+	if err != nil {
+		return "", err
+	}
+	if current, err := os.ReadFile(path); err == nil && !bytes.Contains(current, []byte(hookMarker)) && !force {
+		return "", fmt.Errorf("%s already exists and was not installed by tgcom: use --force to replace it", path)
+	}
+
+	command := fmt.Sprintf("exec %q hook run", executable)
+	if fix {
+		command += " --fix"
+	}
+	script := "#!/bin/sh\n" + hookMarker + "\n" + command + "\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		return "", err
+	}
+	return path, os.Chmod(path, 0755)
+}
+
+/* removes the pre-commit hook installed by tgcom */
+func UninstallHook() (string, error) {
+	path, err := hookPath()
+	if err != nil {
+		return "", err
+	}
+	current, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if !bytes.Contains(current, []byte(hookMarker)) {
+		return "", fmt.Errorf("%s was not installed by tgcom", path)
+	}
+	return path, os.Remove(path)
+}
+
+/* returns the path of the pre-commit hook, taking care of core.hooksPath and of worktrees */
+func hookPath() (string, error) {
+	out, err := gitOutput("rev-parse", "--git-path", "hooks/pre-commit")
+	if err != nil {
+		return "", err
+	}
+	path := strings.TrimSpace(out)
+	if err := os.MkdirAll(strings.TrimSuffix(path, "pre-commit"), 0755); err != nil {
+		return "", err
+	}
+	return path, nil
+}
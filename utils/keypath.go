@@ -0,0 +1,320 @@
+package utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+/* In configuration files a setting is selected with the path of its key (e.g. "server.debug") instead of its lines.
+The lines of the key are the line of the key itself and all the lines of its value, so that commenting them leaves a
+valid document: the nested block of a yaml key, a multi-line array or string of a toml key, a whole toml or ini table
+when the path names a table. The key is searched first among the lines that are not commented and then among the
+commented ones, so that a key commented with this function can be uncommented with the same path */
+
+/* Take in input the name of a yaml, toml, ini or .env file, the path of a key, the action to do and dryrun, and
+modify the lines of the key with ChangeFileLine. The errors are returned, as by the Engine method */
+func ChangeFileKey(filename string, key string, action string, dryrun bool) error {
+	return DefaultEngine.ChangeFileKey(filename, key, Action(action), dryrun)
+}
+
+/* same as the function ChangeFileKey, but on the filesystem of the engine and returning the errors */
+func (e *Engine) ChangeFileKey(filename string, key string, action Action, dryrun bool) error {
+	lines, err := e.readLines(filename)
+	if err != nil {
+		return ioErrorf("failed to open file: %w", err)
+	}
+	start, end, err := FindKeyLines(filename, lines, key)
+	if err != nil {
+		return err
+	}
+	return e.ChangeFileLine(filename, fmt.Sprintf("%d-%d", start, end), action, dryrun)
+}
+
+/* returns the first and last line (1-based) of the key in the lines of the file */
+func FindKeyLines(filename string, lines []string, key string) (startLine int, endLine int, err error) {
+	char, err := CommentCharsFor(filename)
+	if err != nil {
+		return 0, 0, err
+	}
+	var find func([]string, []string) (int, int, bool)
+	extension := filepath.Ext(filename)
+	if strings.HasPrefix(filepath.Base(filename), ".env") {
+		extension = ".env"
+	}
+	switch extension {
+	case ".yaml", ".yml":
+		find = findYAMLKey
+	case ".toml":
+		find = findTOMLKey
+	case ".ini", ".cfg", ".conf":
+		find = findINIKey
+	case ".env":
+		find = findEnvKey
+	default:
+		return 0, 0, usageErrorf("key selection is not supported for %s files", extension)
+	}
+
+	segments := strings.Split(key, ".")
+	if start, end, ok := find(lines, segments); ok {
+		return start + 1, end + 1, nil
+	}
+	// look for the key among the commented lines
+	views := make([]string, len(lines))
+	for i, lineContent := range lines {
+		views[i] = Uncomment(lineContent, char)
+	}
+	if start, end, ok := find(views, segments); ok {
+		return start + 1, end + 1, nil
+	}
+	return 0, 0, noMatchErrorf("key %q not found in %s", key, filename)
+}
+
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+func isBlankOrComment(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, ";")
+}
+
+/* removes the quotes around a key, e.g. "debug" or 'debug' */
+func unquoteKey(key string) string {
+	key = strings.TrimSpace(key)
+	if len(key) >= 2 && (key[0] == '"' || key[0] == '\'') && key[len(key)-1] == key[0] {
+		return key[1 : len(key)-1]
+	}
+	return key
+}
+
+/* drops the blank and comment lines at the end of a block, they belong to what follows */
+func trimBlockEnd(lines []string, start int, end int) int {
+	for end > start && isBlankOrComment(lines[end]) {
+		end--
+	}
+	return end
+}
+
+/* yaml: the value of a key is made of the following lines indented more than the key, plus the items of a list
+written at the same indentation of the key */
+func findYAMLKey(lines []string, segments []string) (int, int, bool) {
+	from, to := 0, len(lines)-1
+	start, end := -1, -1
+	for _, segment := range segments {
+		// the keys of a mapping are at the indentation of its first line
+		childIndent := -1
+		for i := from; i <= to; i++ {
+			if !isBlankOrComment(lines[i]) && strings.TrimSpace(lines[i]) != "---" {
+				childIndent = indentation(lines[i])
+				break
+			}
+		}
+		if childIndent < 0 {
+			return 0, 0, false
+		}
+
+		start = -1
+		for i := from; i <= to; i++ {
+			if indentation(lines[i]) != childIndent || isBlankOrComment(lines[i]) {
+				continue
+			}
+			name, _, ok := strings.Cut(strings.TrimSpace(lines[i]), ":")
+			if ok && unquoteKey(name) == segment {
+				start = i
+				break
+			}
+		}
+		if start < 0 {
+			return 0, 0, false
+		}
+
+		end = start
+		for i := start + 1; i < len(lines); i++ {
+			trimmed := strings.TrimSpace(lines[i])
+			if isBlankOrComment(trimmed) || indentation(lines[i]) > childIndent ||
+				(indentation(lines[i]) == childIndent && strings.HasPrefix(trimmed, "- ")) {
+				end = i
+				continue
+			}
+			break
+		}
+		end = trimBlockEnd(lines, start, end)
+		from, to = start+1, end
+	}
+	return start, end, true
+}
+
+/* toml: a path can name a table, a key of a table ([server] debug = ...) or a dotted key (server.debug = ...). Multi
+line arrays, inline tables and strings are part of the value, so their lines are never taken for table headers even
+when they start with "[" */
+func findTOMLKey(lines []string, segments []string) (int, int, bool) {
+	key := strings.Join(segments, ".")
+	table := ""
+	tableStart := -1 // line of the header of the table named by key, if found
+	for i := 0; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if isBlankOrComment(trimmed) {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "[") {
+			if tableStart >= 0 {
+				return tableStart, trimBlockEnd(lines, tableStart, i-1), true
+			}
+			table = normalizeTOMLKey(strings.Trim(trimmed[:strings.LastIndex(trimmed, "]")+1], "[]"))
+			if table == key {
+				tableStart = i
+			}
+			continue
+		}
+		name, value, ok := strings.Cut(trimmed, "=")
+		if !ok {
+			continue
+		}
+		end := tomlValueEnd(lines, i, strings.TrimSpace(value))
+		fullName := normalizeTOMLKey(name)
+		if table != "" {
+			fullName = table + "." + fullName
+		}
+		if fullName == key && tableStart < 0 {
+			return i, end, true
+		}
+		i = end
+	}
+	if tableStart >= 0 {
+		return tableStart, trimBlockEnd(lines, tableStart, len(lines)-1), true
+	}
+	return 0, 0, false
+}
+
+/* removes spaces and quotes from a toml key, so that "a . 'b'" becomes "a.b" */
+func normalizeTOMLKey(name string) string {
+	parts := strings.Split(name, ".")
+	for i := range parts {
+		parts[i] = unquoteKey(parts[i])
+	}
+	return strings.Join(parts, ".")
+}
+
+/* returns the last line of a toml value starting in line start */
+func tomlValueEnd(lines []string, start int, value string) int {
+	for _, quotes := range []string{`"""`, `'''`} {
+		if strings.HasPrefix(value, quotes) {
+			if strings.Contains(value[len(quotes):], quotes) {
+				return start
+			}
+			for j := start + 1; j < len(lines); j++ {
+				if strings.Contains(lines[j], quotes) {
+					return j
+				}
+			}
+			return len(lines) - 1
+		}
+	}
+	if !strings.HasPrefix(value, "[") && !strings.HasPrefix(value, "{") {
+		return start
+	}
+
+	// count the brackets outside of strings until they are all closed
+	depth := 0
+	for j := start; j < len(lines); j++ {
+		text := value
+		if j > start {
+			text = lines[j]
+		}
+		inString := byte(0)
+		for k := 0; k < len(text); k++ {
+			c := text[k]
+			switch {
+			case inString != 0:
+				if c == '\\' && inString == '"' {
+					k++
+				} else if c == inString {
+					inString = 0
+				}
+			case c == '"' || c == '\'':
+				inString = c
+			case c == '#':
+				k = len(text)
+			case c == '[' || c == '{':
+				depth++
+			case c == ']' || c == '}':
+				depth--
+			}
+		}
+		if depth <= 0 {
+			return j
+		}
+	}
+	return len(lines) - 1
+}
+
+/* ini: "section" names a whole section, "section.key" a key of the section (with its indented continuation lines).
+Keys before the first section are found without the section name */
+func findINIKey(lines []string, segments []string) (int, int, bool) {
+	section := ""
+	name := segments[len(segments)-1]
+	if len(segments) > 1 {
+		section = strings.Join(segments[:len(segments)-1], ".")
+	}
+	key := strings.Join(segments, ".")
+
+	current := ""
+	for i, lineContent := range lines {
+		trimmed := strings.TrimSpace(lineContent)
+		if isBlankOrComment(trimmed) {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			current = strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+			if current == key {
+				end := i
+				for j := i + 1; j < len(lines); j++ {
+					if t := strings.TrimSpace(lines[j]); strings.HasPrefix(t, "[") && strings.HasSuffix(t, "]") {
+						break
+					}
+					end = j
+				}
+				return i, trimBlockEnd(lines, i, end), true
+			}
+			continue
+		}
+		if current != section {
+			continue
+		}
+		keyName := trimmed
+		if idx := strings.IndexAny(trimmed, "=:"); idx >= 0 {
+			keyName = trimmed[:idx]
+		}
+		if strings.TrimSpace(keyName) == name {
+			end := i
+			for j := i + 1; j < len(lines) && lines[j] != "" && indentation(lines[j]) > indentation(lineContent); j++ {
+				end = j
+			}
+			return i, end, true
+		}
+	}
+	return 0, 0, false
+}
+
+/* .env: the key is a variable, its value continues on the next lines only when it is an unclosed quoted string */
+func findEnvKey(lines []string, segments []string) (int, int, bool) {
+	key := strings.Join(segments, ".")
+	for i, lineContent := range lines {
+		trimmed := strings.TrimPrefix(strings.TrimSpace(lineContent), "export ")
+		name, value, ok := strings.Cut(trimmed, "=")
+		if !ok || strings.TrimSpace(name) != key {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(value) > 0 && (value[0] == '"' || value[0] == '\'') && !strings.Contains(value[1:], value[:1]) {
+			for j := i + 1; j < len(lines); j++ {
+				if strings.Contains(lines[j], value[:1]) {
+					return i, j, true
+				}
+			}
+		}
+		return i, i, true
+	}
+	return 0, 0, false
+}
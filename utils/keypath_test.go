@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+type keyTest struct {
+	key        string
+	start, end int
+}
+
+/* checks the lines found by FindKeyLines for every key of tests, and that a missing key is reported */
+func checkKeyLines(t *testing.T, filename string, content string, tests []keyTest) {
+	t.Helper()
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	for _, test := range tests {
+		start, end, err := FindKeyLines(filename, lines, test.key)
+		if err != nil || start != test.start || end != test.end {
+			t.Errorf("%s %q: lines %d-%d (%v), want %d-%d", filename, test.key, start, end, err, test.start, test.end)
+		}
+	}
+	if _, _, err := FindKeyLines(filename, lines, "missing.key"); err == nil {
+		t.Errorf("%s: no error for a missing key", filename)
+	}
+}
+
+func TestFindYAMLKey(t *testing.T) {
+	checkKeyLines(t, "config.yaml", `server:
+  port: 8080
+  debug:
+    level: 2
+    verbose: true
+
+  hosts:
+  - a
+  - b
+# debug: false
+client:
+  timeout: 3
+`, []keyTest{
+		{"server", 1, 9},
+		{"server.port", 2, 2},
+		{"server.debug", 3, 5},
+		{"server.debug.verbose", 5, 5},
+		{"server.hosts", 7, 9},
+		{"client.timeout", 12, 12},
+	})
+	// a key commented by tgcom is found again to uncomment it
+	checkKeyLines(t, "config.yml", "a: 1\n# b:\n#   c: 2\n", []keyTest{{"b", 2, 3}, {"b.c", 3, 3}})
+}
+
+func TestFindTOMLKey(t *testing.T) {
+	checkKeyLines(t, "config.toml", `title = "x"
+matrix = [
+  [1, 2],
+  [3, 4],
+]
+"quoted" = 1
+
+[server]
+debug = true
+ports = [
+  8080,
+  "[not a table]",
+]
+text = """
+[not a table either]
+"""
+
+[server.tls]
+cert = "a"
+[[products]]
+name = "p"
+`, []keyTest{
+		{"title", 1, 1},
+		{"matrix", 2, 5},
+		{"quoted", 6, 6},
+		{"server", 8, 16},
+		{"server.debug", 9, 9},
+		{"server.ports", 10, 13},
+		{"server.text", 14, 16},
+		{"server.tls", 18, 19},
+		{"server.tls.cert", 19, 19},
+		{"products.name", 21, 21},
+	})
+}
+
+func TestFindINIKey(t *testing.T) {
+	checkKeyLines(t, "setup.cfg", `top = 1
+[metadata]
+name = x
+description = a
+  long text
+; comment
+
+[options]
+debug: true
+`, []keyTest{
+		{"top", 1, 1},
+		{"metadata", 2, 5},
+		{"metadata.description", 4, 5},
+		{"options.debug", 9, 9},
+	})
+}
+
+func TestFindEnvKey(t *testing.T) {
+	checkKeyLines(t, ".env.local", `DEBUG=1
+export API_URL=http://localhost
+KEY="-----BEGIN
+abc
+-----END"
+# OLD=1
+`, []keyTest{
+		{"DEBUG", 1, 1},
+		{"API_URL", 2, 2},
+		{"KEY", 3, 5},
+		{"OLD", 6, 6},
+	})
+}
+
+func TestFindKeyLinesUnsupported(t *testing.T) {
+	if _, _, err := FindKeyLines("main.go", []string{"x := 1"}, "x"); err == nil {
+		t.Error("no error for a go file")
+	}
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+/* Logger receives the diagnostics of the package: the language detected for each file, the lines a selection resolved
+to, the backup and temporary files and how long each write took. By default they are discarded, the command line
+replaces it with a logger on stderr (see the flags -v, -vv, --quiet and --log-format), so stdout keeps only the
+output of the commands */
+var Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+/* logs the language of a file, chosen with its extension, and its comment characters */
+func logLanguage(filename string) {
+	if !Logger.Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+	language, err := LanguageFor(filename)
+	if err != nil {
+		return
+	}
+	Logger.Debug("language detected", "file", filename, "language", string(language), "comment", language.CommentChars(), "regions", IsEmbedded(filename))
+}
+
+/* logs the lines of a file selected by selection */
+func logSelection(filename string, lines []string, selection Selection) {
+	if !Logger.Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+	if selected, err := SelectLines(filename, lines, selection); err == nil {
+		logSelected(filename, selection, selected)
+	}
+}
+
+/* logs the lines for which selected is true, as ranges in the syntax of -l (e.g. "3-5,8") */
+func logSelected(filename string, selection Selection, selected []bool) {
+	if !Logger.Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+	Logger.Debug("selection resolved", "file", filename, "selection", selection, "lines", lineRanges(selected))
+}
+
+/* returns the selected lines as ranges in the syntax of -l, "" if no line is selected */
+func lineRanges(selected []bool) string {
+	var ranges []string
+	for i := 0; i < len(selected); i++ {
+		if !selected[i] {
+			continue
+		}
+		start := i
+		for i+1 < len(selected) && selected[i+1] {
+			i++
+		}
+		if start == i {
+			ranges = append(ranges, strconv.Itoa(start+1))
+		} else {
+			ranges = append(ranges, strconv.Itoa(start+1)+"-"+strconv.Itoa(i+1))
+		}
+	}
+	return strings.Join(ranges, ",")
+}
+
+/* the fields of a selection that have been given, for the logs */
+func (s Selection) LogValue() slog.Value {
+	var attrs []slog.Attr
+	for _, field := range []struct{ key, value string }{
+		{"lines", s.Lines}, {"start-label", s.StartLabel}, {"end-label", s.EndLabel},
+		{"regex", s.Regex}, {"symbol", s.Symbol}, {"key", s.Key},
+	} {
+		if field.value != "" {
+			attrs = append(attrs, slog.String(field.key, field.value))
+		}
+	}
+	return slog.GroupValue(attrs...)
+}
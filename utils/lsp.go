@@ -0,0 +1,440 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+/* ServeLSP is a Language Server Protocol server, so that any editor with an LSP client can comment and uncomment code
+with tgcom without a plugin of its own. It works on the buffers of the editor, that are sent by the client when they
+are opened and changed, and never reads or writes the files: every change is sent back to the editor as an edit.
+It offers:
+  - the code actions "Comment selection" and "Uncomment selection" on the selected lines
+  - the code action "Toggle tgcom block NAME" when the cursor is inside a named block (see BlockLabels)
+  - a symbol and a code lens for every named block, the code lens toggles the block when clicked
+
+Messages are JSON-RPC 2.0, each one preceded by a Content-Length header, on the standard input and output */
+
+/* command run by the code lenses, with the uri of the document and the name of the block as arguments */
+const lspToggleBlockCommand = "tgcom.toggleBlock"
+
+/* error codes of JSON-RPC */
+const (
+	rpcParseError     = -32700
+	rpcInvalidParams  = -32602
+	rpcMethodNotFound = -32601
+)
+
+/* symbol kind used for the blocks (Namespace) */
+const lspSymbolKindBlock = 3
+
+/* rpcMessage is any message received from the client: a request, a notification (no ID) or a response (no Method) */
+type rpcMessage struct {
+	ID     *json.RawMessage `json:"id"`
+	Method string           `json:"method"`
+	Params json.RawMessage  `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspTextEdit struct {
+	Range   lspRange `json:"range"`
+	NewText string   `json:"newText"`
+}
+
+type lspWorkspaceEdit struct {
+	Changes map[string][]lspTextEdit `json:"changes"`
+}
+
+type lspCommand struct {
+	Title     string        `json:"title"`
+	Command   string        `json:"command"`
+	Arguments []interface{} `json:"arguments,omitempty"`
+}
+
+type lspCodeAction struct {
+	Title string            `json:"title"`
+	Kind  string            `json:"kind"`
+	Edit  *lspWorkspaceEdit `json:"edit"`
+}
+
+type lspCodeLens struct {
+	Range   lspRange    `json:"range"`
+	Command *lspCommand `json:"command"`
+}
+
+type lspDocumentSymbol struct {
+	Name           string   `json:"name"`
+	Detail         string   `json:"detail"`
+	Kind           int      `json:"kind"`
+	Range          lspRange `json:"range"`
+	SelectionRange lspRange `json:"selectionRange"`
+}
+
+type lspTextDocument struct {
+	URI        string `json:"uri"`
+	LanguageID string `json:"languageId"`
+	Text       string `json:"text"`
+}
+
+/* params of the requests and notifications on a document */
+type lspDocumentParams struct {
+	TextDocument   lspTextDocument `json:"textDocument"`
+	Range          lspRange        `json:"range"`
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+}
+
+type lspExecuteCommandParams struct {
+	Command   string            `json:"command"`
+	Arguments []json.RawMessage `json:"arguments"`
+}
+
+/* lspDocument is a buffer opened in the editor */
+type lspDocument struct {
+	path       string
+	languageID string
+	lines      []string
+}
+
+type lspServer struct {
+	reader    *bufio.Reader
+	writer    io.Writer
+	documents map[string]*lspDocument
+	nextID    int
+	shutdown  bool
+}
+
+/* serves the client connected to in and out until it sends the exit notification or closes the connection */
+func ServeLSP(in io.Reader, out io.Writer) error {
+	s := &lspServer{reader: bufio.NewReader(in), writer: out, documents: map[string]*lspDocument{}}
+	for {
+		body, err := s.readMessage()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var msg rpcMessage
+		if err := json.Unmarshal(body, &msg); err != nil {
+			s.replyError(nil, rpcParseError, err.Error())
+			continue
+		}
+		if msg.Method == "" {
+			// response to a request of the server (workspace/applyEdit), nothing to do
+			continue
+		}
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return fmt.Errorf("lsp: exit without shutdown")
+			}
+			return nil
+		}
+
+		result, rpcErr := s.handle(msg)
+		if msg.ID == nil {
+			continue
+		}
+		if rpcErr != nil {
+			s.replyError(msg.ID, rpcErr.Code, rpcErr.Message)
+		} else if err := s.write(map[string]interface{}{"jsonrpc": "2.0", "id": msg.ID, "result": result}); err != nil {
+			return err
+		}
+	}
+}
+
+/* reads the body of the next message */
+func (s *lspServer) readMessage() ([]byte, error) {
+	header, err := textproto.NewReader(s.reader).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("lsp: invalid Content-Length %q", header.Get("Content-Length"))
+	}
+	body := make([]byte, length)
+	_, err = io.ReadFull(s.reader, body)
+	return body, err
+}
+
+/* writes a message with its header */
+func (s *lspServer) write(msg interface{}) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.writer, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+func (s *lspServer) replyError(id *json.RawMessage, code int, message string) {
+	s.write(map[string]interface{}{"jsonrpc": "2.0", "id": id, "error": rpcError{Code: code, Message: message}})
+}
+
+/* sends a request to the client; its response is ignored */
+func (s *lspServer) request(method string, params interface{}) error {
+	s.nextID++
+	return s.write(map[string]interface{}{"jsonrpc": "2.0", "id": s.nextID, "method": method, "params": params})
+}
+
+/* handles a request or a notification, returning the result of the request */
+func (s *lspServer) handle(msg rpcMessage) (interface{}, *rpcError) {
+	switch msg.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":       1, // the client sends the whole text on every change
+				"codeActionProvider":     true,
+				"documentSymbolProvider": true,
+				"codeLensProvider":       map[string]bool{"resolveProvider": false},
+				"executeCommandProvider": map[string][]string{"commands": {lspToggleBlockCommand}},
+			},
+			"serverInfo": map[string]string{"name": "tgcom"},
+		}, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "workspace/executeCommand":
+		var params lspExecuteCommandParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+		return s.executeCommand(params)
+	}
+
+	var params lspDocumentParams
+	if len(msg.Params) > 0 {
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+	}
+	uri := params.TextDocument.URI
+	switch msg.Method {
+	case "textDocument/didOpen":
+		s.documents[uri] = &lspDocument{path: uriPath(uri), languageID: params.TextDocument.LanguageID, lines: splitText(params.TextDocument.Text)}
+		return nil, nil
+	case "textDocument/didChange":
+		if doc, ok := s.documents[uri]; ok && len(params.ContentChanges) > 0 {
+			doc.lines = splitText(params.ContentChanges[len(params.ContentChanges)-1].Text)
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		delete(s.documents, uri)
+		return nil, nil
+	case "textDocument/codeAction":
+		return s.codeActions(uri, params.Range), nil
+	case "textDocument/documentSymbol":
+		return s.documentSymbols(uri), nil
+	case "textDocument/codeLens":
+		return s.codeLenses(uri), nil
+	}
+	if msg.ID == nil {
+		// notifications that are not handled are ignored, e.g. initialized and $/cancelRequest
+		return nil, nil
+	}
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: "method not found: " + msg.Method}
+}
+
+/* returns the code actions for the selected lines of the document */
+func (s *lspServer) codeActions(uri string, r lspRange) []lspCodeAction {
+	actions := []lspCodeAction{}
+	doc, ok := s.documents[uri]
+	if !ok || len(doc.lines) == 0 {
+		return actions
+	}
+	start, end := r.Start.Line, r.End.Line
+	// a selection that ends at the beginning of a line does not include that line
+	if end > start && r.End.Character == 0 {
+		end--
+	}
+	if start >= len(doc.lines) {
+		return actions
+	}
+	if end >= len(doc.lines) {
+		end = len(doc.lines) - 1
+	}
+
+	selection := Selection{Lines: fmt.Sprintf("%d-%d", start+1, end+1)}
+	for _, want := range []BlockState{Commented, Uncommented} {
+		if edit := s.ensureEdit(uri, doc, selection, want); edit != nil {
+			title := "Comment selection"
+			if want == Uncommented {
+				title = "Uncomment selection"
+			}
+			actions = append(actions, lspCodeAction{Title: title, Kind: "refactor.rewrite", Edit: edit})
+		}
+	}
+
+	for _, block := range s.blocks(doc) {
+		if block.Start-1 > start || block.End-1 < start {
+			continue
+		}
+		if edit := s.toggleBlockEdit(uri, doc, block); edit != nil {
+			actions = append(actions, lspCodeAction{Title: "Toggle tgcom block " + block.Name, Kind: "refactor.rewrite", Edit: edit})
+		}
+	}
+	return actions
+}
+
+/* returns a symbol for every named block of the document */
+func (s *lspServer) documentSymbols(uri string) []lspDocumentSymbol {
+	symbols := []lspDocumentSymbol{}
+	doc, ok := s.documents[uri]
+	if !ok {
+		return symbols
+	}
+	for _, block := range s.blocks(doc) {
+		symbols = append(symbols, lspDocumentSymbol{
+			Name:           block.Name,
+			Detail:         string(block.State),
+			Kind:           lspSymbolKindBlock,
+			Range:          lspRange{Start: lspPosition{Line: block.Start - 1}, End: lspPosition{Line: block.End - 1, Character: utf16Len(doc.lines[block.End-1])}},
+			SelectionRange: lineRange(doc.lines, block.Start-1),
+		})
+	}
+	return symbols
+}
+
+/* returns a code lens on the start label of every named block, that toggles the block */
+func (s *lspServer) codeLenses(uri string) []lspCodeLens {
+	lenses := []lspCodeLens{}
+	doc, ok := s.documents[uri]
+	if !ok {
+		return lenses
+	}
+	for _, block := range s.blocks(doc) {
+		lenses = append(lenses, lspCodeLens{
+			Range: lineRange(doc.lines, block.Start-1),
+			Command: &lspCommand{
+				Title:     fmt.Sprintf("Toggle tgcom block %s (%s)", block.Name, block.State),
+				Command:   lspToggleBlockCommand,
+				Arguments: []interface{}{uri, block.Name},
+			},
+		})
+	}
+	return lenses
+}
+
+/* runs the command of the code lenses: the edit that toggles the block is sent to the client with workspace/applyEdit */
+func (s *lspServer) executeCommand(params lspExecuteCommandParams) (interface{}, *rpcError) {
+	if params.Command != lspToggleBlockCommand {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "unknown command: " + params.Command}
+	}
+	var uri, name string
+	if len(params.Arguments) != 2 || json.Unmarshal(params.Arguments[0], &uri) != nil || json.Unmarshal(params.Arguments[1], &name) != nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: lspToggleBlockCommand + " needs the uri of the document and the name of the block"}
+	}
+	doc, ok := s.documents[uri]
+	if !ok {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "document not open: " + uri}
+	}
+	for _, block := range s.blocks(doc) {
+		if block.Name != name {
+			continue
+		}
+		if edit := s.toggleBlockEdit(uri, doc, block); edit != nil {
+			s.request("workspace/applyEdit", map[string]interface{}{"label": "Toggle tgcom block " + name, "edit": edit})
+		}
+		return nil, nil
+	}
+	return nil, &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("block %q not found", name)}
+}
+
+/* returns the named blocks of the document, the ones with unmatched labels are ignored */
+func (s *lspServer) blocks(doc *lspDocument) []Block {
+	blocks, _ := FindBlocks(doc.path, doc.lines, "", "")
+	return blocks
+}
+
+/* returns the edit that uncomments a commented block and comments the other ones */
+func (s *lspServer) toggleBlockEdit(uri string, doc *lspDocument, block Block) *lspWorkspaceEdit {
+	if block.End-block.Start < 2 {
+		return nil
+	}
+	want := Commented
+	if block.State == Commented {
+		want = Uncommented
+	}
+	return s.ensureEdit(uri, doc, Selection{Lines: fmt.Sprintf("%d-%d", block.Start+1, block.End-1)}, want)
+}
+
+/* returns the edit that brings the selected lines of the document in the state want, or nil if they already are */
+func (s *lspServer) ensureEdit(uri string, doc *lspDocument, selection Selection, want BlockState) *lspWorkspaceEdit {
+	language := ""
+	if _, err := CommentCharsFor(doc.path); err != nil && !IsEmbedded(doc.path) {
+		// files without a known extension (e.g. untitled buffers) use the language of the editor
+		language = doc.languageID
+	}
+	newLines, err := changeLines(doc.path, doc.lines, selection, language, ensureChange(want))
+	if err != nil {
+		return nil
+	}
+
+	var edits []lspTextEdit
+	for i := range doc.lines {
+		if newLines[i] != doc.lines[i] {
+			edits = append(edits, lspTextEdit{Range: lineRange(doc.lines, i), NewText: newLines[i]})
+		}
+	}
+	if len(edits) == 0 {
+		return nil
+	}
+	return &lspWorkspaceEdit{Changes: map[string][]lspTextEdit{uri: edits}}
+}
+
+/* returns the range of the content of line i */
+func lineRange(lines []string, i int) lspRange {
+	return lspRange{Start: lspPosition{Line: i}, End: lspPosition{Line: i, Character: utf16Len(lines[i])}}
+}
+
+/* positions in LSP count UTF-16 code units */
+func utf16Len(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
+
+/* splits the text of a buffer in lines. The carriage returns of CRLF files are dropped, they stay in the buffer
+because the edits replace only the content of the lines */
+func splitText(text string) []string {
+	lines := strings.Split(text, "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSuffix(lines[i], "\r")
+	}
+	return lines
+}
+
+/* returns the path of a file:// uri, or the uri itself for the other schemes (e.g. untitled:) */
+func uriPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
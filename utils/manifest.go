@@ -0,0 +1,317 @@
+package utils
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+/* A manifest is a yaml file that describes many modifications at once, so that they don't have to be written as long
+-f strings in shell scripts. Example:
+
+	vars:
+	  block: debug
+	operations:
+	  - name: disable debug logs
+	    files: ["cmd/*.go", "scripts/*.sh"]
+	    start-label: "${block}-start"
+	    end-label: "${block}-end"
+	    action: comment
+	  - name: mock backend
+	    files: ["config/app.yaml"]
+	    key: backend.url
+	    action: uncomment
+
+Variables are written as ${name}: they are taken from vars (or from the values passed on the command line) and, with
+the env. prefix, from the environment (${env.HOME}). File patterns are relative to the directory of the manifest and
+"**" matches any number of directories, e.g. "scripts/**" matches all the files under scripts */
+type Manifest struct {
+	Vars       map[string]string `yaml:"vars" json:"vars"`
+	Operations []Operation       `yaml:"operations" json:"operations"`
+}
+
+/* Operation is a modification described in a manifest: the selection is applied to every file matching Files.
+Language overrides the language chosen with the extension of the files */
+type Operation struct {
+	Name       string   `yaml:"name" json:"name"`
+	Files      []string `yaml:"files" json:"files"`
+	Lines      string   `yaml:"lines" json:"lines"`
+	StartLabel string   `yaml:"start-label" json:"start-label"`
+	EndLabel   string   `yaml:"end-label" json:"end-label"`
+	Regex      string   `yaml:"regex" json:"regex"`
+	Symbol     string   `yaml:"symbol" json:"symbol"`
+	Key        string   `yaml:"key" json:"key"`
+	Action     string   `yaml:"action" json:"action"`
+	Language   string   `yaml:"language" json:"language"`
+}
+
+/* FileResult tells how many lines of a file have been modified, or why the file could not be modified. Diff is set only
+by the previews of tgcom serve */
+type FileResult struct {
+	File    string `json:"file"`
+	Changed int    `json:"changed"`
+	Diff    string `json:"diff,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+/* OperationResult is the result of an operation of a manifest on each of its files */
+type OperationResult struct {
+	Name  string       `json:"name"`
+	Files []FileResult `json:"files"`
+	Error string       `json:"error,omitempty"`
+}
+
+/* returns true if the operation or one of its files failed */
+func (r OperationResult) Failed() bool {
+	if r.Error != "" {
+		return true
+	}
+	for _, file := range r.Files {
+		if file.Error != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (o Operation) selection() Selection {
+	return Selection{
+		Lines:      o.Lines,
+		StartLabel: o.StartLabel,
+		EndLabel:   o.EndLabel,
+		Regex:      o.Regex,
+		Symbol:     o.Symbol,
+		Key:        o.Key,
+	}
+}
+
+/* reads a manifest and replaces its variables. vars overrides the variables declared in the manifest */
+func LoadManifest(path string, vars map[string]string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var manifest Manifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %v", path, err)
+	}
+	if manifest.Vars == nil {
+		manifest.Vars = map[string]string{}
+	}
+	for name, value := range vars {
+		manifest.Vars[name] = value
+	}
+
+	for i := range manifest.Operations {
+		op := &manifest.Operations[i]
+		if op.Name == "" {
+			op.Name = fmt.Sprintf("operation %d", i+1)
+		}
+		fields := []*string{&op.Name, &op.Lines, &op.StartLabel, &op.EndLabel, &op.Regex, &op.Symbol, &op.Key,
+			&op.Action, &op.Language}
+		for j := range op.Files {
+			fields = append(fields, &op.Files[j])
+		}
+		for _, field := range fields {
+			if *field, err = expandVars(*field, manifest.Vars); err != nil {
+				return nil, fmt.Errorf("%s: %w", op.Name, err)
+			}
+		}
+	}
+	return &manifest, nil
+}
+
+var varRegexp = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_.-]*)\}`)
+
+/* replaces ${name} with the value of the variable and ${env.NAME} with the environment variable NAME. Only this syntax
+is used so that regular expressions containing $ are left as they are */
+func expandVars(s string, vars map[string]string) (string, error) {
+	var err error
+	expanded := varRegexp.ReplaceAllStringFunc(s, func(match string) string {
+		name := varRegexp.FindStringSubmatch(match)[1]
+		if strings.HasPrefix(name, "env.") {
+			value, ok := os.LookupEnv(strings.TrimPrefix(name, "env."))
+			if !ok {
+				err = fmt.Errorf("environment variable %s is not set", strings.TrimPrefix(name, "env."))
+			}
+			return value
+		}
+		value, ok := vars[name]
+		if !ok {
+			err = fmt.Errorf("variable %s is not defined", name)
+		}
+		return value
+	})
+	return expanded, err
+}
+
+/* executes all the operations of the manifest, in order. The file patterns are relative to baseDir. An operation that
+fails does not stop the following ones */
+func (m *Manifest) Apply(baseDir string, dryrun bool) []OperationResult {
+	results := make([]OperationResult, 0, len(m.Operations))
+	for _, op := range m.Operations {
+		results = append(results, op.apply(baseDir, dryrun))
+	}
+	return results
+}
+
+func (o Operation) apply(baseDir string, dryrun bool) OperationResult {
+	return o.run(baseDir, func(file string, action Action) FileResult {
+		fileResult := FileResult{File: file}
+		changed, err := ChangeFileSelection(file, o.selection(), action, o.Language, dryrun)
+		fileResult.Changed = changed
+		if err != nil {
+			fileResult.Error = err.Error()
+		}
+		return fileResult
+	})
+}
+
+/* checks the operation, expands its file patterns and calls change on every file with the action to do */
+func (o Operation) run(baseDir string, change func(file string, action Action) FileResult) OperationResult {
+	result := OperationResult{Name: o.Name}
+	action := ActionToggle
+	if o.Action != "" {
+		var err error
+		if action, err = ParseAction(o.Action); err != nil {
+			result.Error = err.Error()
+			return result
+		}
+	}
+	if len(o.Files) == 0 {
+		result.Error = "no files given"
+		return result
+	}
+
+	var files []string
+	for _, pattern := range o.Files {
+		matches, err := ExpandGlob(baseDir, pattern)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		if len(matches) == 0 {
+			result.Error = fmt.Sprintf("no file matches %s", pattern)
+			return result
+		}
+		files = append(files, matches...)
+	}
+
+	for _, file := range files {
+		result.Files = append(result.Files, change(file, action))
+	}
+	return result
+}
+
+/* returns the comment characters of a language, given either as a key of CommentChars (e.g. "Bash") or as a name
+used in markdown code blocks (e.g. "bash") */
+func LanguageCommentChars(language string) (string, error) {
+	lang, err := ParseLanguage(language)
+	if err != nil {
+		return "", err
+	}
+	return lang.CommentChars(), nil
+}
+
+/* Take in input a file, the lines to modify, the action, the language to use instead of the one of the extension (it
+can be empty) and dryrun, and returns the number of lines modified. With dryrun the modified lines are printed and
+the file is left untouched */
+func ChangeFileSelection(filename string, selection Selection, action Action, language string, dryrun bool) (int, error) {
+	return DefaultEngine.ChangeFileSelection(filename, selection, action, language, dryrun)
+}
+
+/* same as the function ChangeFileSelection, but on the filesystem of the engine */
+func (e *Engine) ChangeFileSelection(filename string, selection Selection, action Action, language string, dryrun bool) (int, error) {
+	if err := checkAction(action); err != nil {
+		return 0, err
+	}
+	return e.changeFileLines(filename, selection, language, dryrun, func(lineContent string, char string) string {
+		return applyAction(lineContent, char, action)
+	})
+}
+
+/* same as ChangeFileSelection, but on lines that are already in memory (e.g. the buffer of an editor): returns the new
+lines and leaves lines untouched. filename is used only to choose the language */
+func ChangeLines(filename string, lines []string, selection Selection, action Action, language string) ([]string, error) {
+	if err := checkAction(action); err != nil {
+		return nil, err
+	}
+	return changeLines(filename, lines, selection, language, func(lineContent string, char string) string {
+		return applyAction(lineContent, char, action)
+	})
+}
+
+/* returns the files matching pattern, relative to baseDir if the pattern is not absolute. Besides the patterns of
+filepath.Match, "**" matches any number of directories. The files matching IgnorePatterns are left out */
+func ExpandGlob(baseDir string, pattern string) ([]string, error) {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(baseDir, pattern)
+	}
+	if !strings.Contains(pattern, "**") {
+		matches, err := filepath.Glob(pattern)
+		return withoutIgnored(regularFiles(matches)), err
+	}
+
+	// walk the directory before the first "**" and match the whole path of every file
+	root := filepath.Dir(pattern[:strings.Index(pattern, "**")] + "x")
+	patternParts := strings.Split(filepath.ToSlash(pattern), "/")
+	var matches []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		ok, err := matchParts(patternParts, strings.Split(filepath.ToSlash(path), "/"))
+		if ok {
+			matches = append(matches, path)
+		}
+		return err
+	})
+	sort.Strings(matches)
+	return withoutIgnored(matches), err
+}
+
+/* matches the parts of a path with the parts of a pattern, "**" matches zero or more parts */
+func matchParts(pattern []string, path []string) (bool, error) {
+	if len(pattern) == 0 {
+		return len(path) == 0, nil
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(path); i++ {
+			if ok, err := matchParts(pattern[1:], path[i:]); ok || err != nil {
+				return ok, err
+			}
+		}
+		return false, nil
+	}
+	if len(path) == 0 {
+		return false, nil
+	}
+	ok, err := filepath.Match(pattern[0], path[0])
+	if !ok || err != nil {
+		return false, err
+	}
+	return matchParts(pattern[1:], path[1:])
+}
+
+/* drops the directories from the result of filepath.Glob */
+func regularFiles(paths []string) []string {
+	var files []string
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			files = append(files, path)
+		}
+	}
+	return files
+}
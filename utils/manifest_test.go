@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExpandGlob(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"main.go", "src/a.go", "src/b.py", "src/pkg/c.go", "src/pkg/deep/d.go", "docs/e.go", ".git/f.go"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a directory whose name matches the patterns is not a file
+	if err := os.MkdirAll(filepath.Join(dir, "src", "dir.go"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		pattern string
+		ignore  []string
+		want    []string
+	}{
+		{"*.go", nil, []string{"main.go"}},
+		{"src/*.go", nil, []string{"src/a.go"}},
+		{"**/*.go", nil, []string{"docs/e.go", "main.go", "src/a.go", "src/pkg/c.go", "src/pkg/deep/d.go"}},
+		{"src/**/*.go", nil, []string{"src/a.go", "src/pkg/c.go", "src/pkg/deep/d.go"}},
+		{"src/**/deep/*.go", nil, []string{"src/pkg/deep/d.go"}},
+		{"src/**", nil, []string{"src/a.go", "src/b.py", "src/pkg/c.go", "src/pkg/deep/d.go"}},
+		{"**/*.go", []string{"pkg"}, []string{"docs/e.go", "main.go", "src/a.go"}},
+		{"**/*.rs", nil, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			defer func(patterns []string) { IgnorePatterns = patterns }(IgnorePatterns)
+			IgnorePatterns = test.ignore
+			matches, err := ExpandGlob(dir, test.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, match := range matches {
+				rel, err := filepath.Rel(dir, match)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, filepath.ToSlash(rel))
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ExpandGlob(%q) = %q, want %q", test.pattern, got, test.want)
+			}
+		})
+	}
+}
+
+func TestMatchParts(t *testing.T) {
+	tests := []struct {
+		pattern []string
+		path    []string
+		want    bool
+	}{
+		{[]string{"**"}, nil, true},
+		{[]string{"**", "*.go"}, []string{"a.go"}, true},
+		{[]string{"**", "*.go"}, []string{"x", "y", "a.go"}, true},
+		{[]string{"x", "**", "y", "**"}, []string{"x", "y"}, true},
+		{[]string{"x", "**", "*.go"}, []string{"z", "a.go"}, false},
+		{[]string{"*.go"}, []string{"x", "a.go"}, false},
+	}
+	for _, test := range tests {
+		if got, err := matchParts(test.pattern, test.path); err != nil || got != test.want {
+			t.Errorf("matchParts(%q, %q) = %v, %v, want %v", test.pattern, test.path, got, err, test.want)
+		}
+	}
+}
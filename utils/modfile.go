@@ -1,633 +1,417 @@
-package utils
-
-import (
-    "bufio"
-    "fmt"
-    "log"
-    "os"
-	"strings"
-	"strconv"
-	"io"
-	"errors"
-	"path/filepath"
-)
-
-/* phylosophy: gli input a queste funzioni devono essere tutti giusti! è nel file della flag che controlli se gli argumment delle flag sono
-giusti */
-
-/* This function is a copy of the previuous function but you use StartLabel and EndLabel instead of line as a string */
-func ChangeFileLabel(filename string, startLabel string, endLabel string, action string, dryrun bool){
-	
-	// Open the file
-    file, err := os.Open(filename)
-    if err != nil {
-        log.Fatalf("failed to open file: %s", err)
-    }
-    // Ensure file is closed at the end
-    defer file.Close()
-
-	char := selectCommentChars(filename)
-
-	switch dryrun {
-	case true:
-		// Create a new scanner for the file
-    	scanner := bufio.NewScanner(file)
-		switch action{
-		case "comment":
-			inSection := false
-    		for scanner.Scan() {
-				lineContent := scanner.Text()
-				// HERE: if lineContent contain the start label, then fai currentLine++, se non contiene endLabel modifica
-				if strings.Contains(lineContent, endLabel){
-					inSection = false
-				}
-
-				if inSection {
-					fmt.Println(lineContent + " " + "->" + " " + Comment(lineContent, char))
-				}
-
-				if strings.Contains(lineContent, startLabel){
-					inSection = true
-				}
-    		}
-			fmt.Println("\n")
-			// Check for scanning errors
-    		if err := scanner.Err(); err != nil {
-        		log.Fatalf("error reading file: %s", err)
-    		}
-		case "uncomment":
-			inSection := false
-    		for scanner.Scan() {
-				lineContent := scanner.Text()
-				// HERE: if lineContent contain the start label, then fai currentLine++, se non contiene endLabel modifica
-				if strings.Contains(lineContent, endLabel){
-					inSection = false
-				}
-
-				if inSection {
-					fmt.Println(lineContent + " " + "->" + " " + Uncomment(lineContent, char))
-				}
-
-				if strings.Contains(lineContent, startLabel){
-					inSection = true
-				}
-    		}
-			fmt.Println("\n")
-			// Check for scanning errors
-    		if err := scanner.Err(); err != nil {
-        		log.Fatalf("error reading file: %s", err)
-    		}
-		case "toggle":
-			inSection := false
-    		for scanner.Scan() {
-				lineContent := scanner.Text()
-				// HERE: if lineContent contain the start label, then fai currentLine++, se non contiene endLabel modifica
-				if strings.Contains(lineContent, endLabel){
-					inSection = false
-				}
-
-				if inSection {
-					fmt.Println(lineContent + " " + "->" + " " + ToggleComments(lineContent, char))
-				}
-				if strings.Contains(lineContent, startLabel){
-					inSection = true
-				}
-    		}
-			fmt.Println("\n")
-			// Check for scanning errors
-    		if err := scanner.Err(); err != nil {
-        		log.Fatalf("error reading file: %s", err)
-    		}
-		}
-	case false:
-		// Create a backup of the original file
-		backupFilename := filename + ".bak"
-		createBackup(filename, backupFilename)
-
-		// Create a temporary file
-		tmpFilename := filename + ".tmp"
-		tmpFile, err := os.Create(tmpFilename)
-		if err != nil {
-			restoreBackup(filename, backupFilename)
-			log.Fatalf("Errore: %v", err)
-		}
-		defer tmpFile.Close()
-
-		_, err = file.Seek(0, io.SeekStart)
-		if err != nil {
-			restoreBackup(filename, backupFilename)
-			tmpFile.Close()
-			os.Remove(tmpFilename)
-			log.Fatalf("Errore: %v", err)
-		}
-
-		err = writeChangesLabel(file, tmpFile, startLabel, endLabel, action, char)
-
-		if err != nil {
-			restoreBackup(filename, backupFilename)
-			tmpFile.Close()
-			os.Remove(tmpFilename)
-			log.Fatalf("Errore: %v", err)
-		}
-
-		if err := file.Close(); err != nil {
-			restoreBackup(filename, backupFilename)
-			tmpFile.Close()
-			os.Remove(tmpFilename)
-			log.Fatalf("Errore: %v", err)
-		}
-
-		// Close the temporary file before renaming
-		if err := tmpFile.Close(); err != nil {
-			os.Remove(tmpFilename)
-			log.Fatalf("Errore: %v", err)
-		}
-
-		// Rename temporary file to original file
-		if err := os.Rename(tmpFilename, filename); err != nil {
-			restoreBackup(filename, backupFilename)
-			log.Fatalf("Errore: %v", err)
-		}
-
-		// Remove backup file after successful processing
-		os.Remove(backupFilename)
-	}
-}
-
-/* Take in input the name of a file in the  current folder, a string that contains info about lines to be commented/uncommented, the action to do (comment,
-uncomment or toggle, if no argument is passed to the flag -a the defualt will be toggle) and dryrun. If true the modifications will be displayed on the
-terminal but will not be saved on the file. Otherwise the files will be modified */
-func ChangeFileLine(filename string, line string, action string, dryrun bool) {
-
-    // Open the file
-    file, err := os.Open(filename)
-    if err != nil {
-        log.Fatalf("failed to open file: %s", err)
-    }
-    // Ensure file is closed at the end
-    defer file.Close()
-
-	char := selectCommentChars(filename)
-
-    // find lines
-	start, end:= FindLines(line)
-
-	switch dryrun {
-	case true:
-		// Create a new scanner for the file
-    	scanner := bufio.NewScanner(file)
-		switch action{
-		case "comment":
-			currentLine := 1
-    		for scanner.Scan() {
-				lineContent := scanner.Text()
-				if start <= currentLine && currentLine <= end {
-					fmt.Println(lineContent + " " + "->" + " " + Comment(lineContent, char))
-				} else {
-    		    fmt.Println(lineContent)
-				}
-				currentLine++
-    		}
-			fmt.Println("\n")
-			// Check for scanning errors
-    		if err := scanner.Err(); err != nil {
-        		log.Fatalf("error reading file: %s", err)
-    		}
-		case "uncomment":
-			currentLine := 1
-    		for scanner.Scan() {
-				lineContent := scanner.Text()
-				if start <= currentLine && currentLine <= end {
-					fmt.Println(lineContent + " " + "->" + " " + Uncomment(lineContent, char))
-				} else {
-    		    fmt.Println(lineContent)
-				}
-				currentLine++
-    		}
-			fmt.Println("\n")
-			// Check for scanning errors
-    		if err := scanner.Err(); err != nil {
-        		log.Fatalf("error reading file: %s", err)
-    		}
-		case "toggle":
-			currentLine := 1
-    		for scanner.Scan() {
-				lineContent := scanner.Text()
-				if start <= currentLine && currentLine <= end {
-					fmt.Println(lineContent + " " + "->" + " " + ToggleComments(lineContent, char))
-				} else {
-    		    fmt.Println(lineContent)
-				}
-				currentLine++
-    		}
-			fmt.Println("\n")
-			// Check for scanning errors
-    		if err := scanner.Err(); err != nil {
-        		log.Fatalf("error reading file: %s", err)
-    		}
-		}
-	case false:
-		// Create a backup of the original file
-		backupFilename := filename + ".bak"
-		createBackup(filename, backupFilename)
-
-		// Create a temporary file
-		tmpFilename := filename + ".tmp"
-		tmpFile, err := os.Create(tmpFilename)
-		if err != nil {
-			restoreBackup(filename, backupFilename)
-			log.Fatalf("Errore: %v", err)
-		}
-		defer tmpFile.Close()
-
-		_, err = file.Seek(0, io.SeekStart)
-		if err != nil {
-			restoreBackup(filename, backupFilename)
-			tmpFile.Close()
-			os.Remove(tmpFilename)
-			log.Fatalf("Errore: %v", err)
-		}
-
-		err = writeChangesLine(file, tmpFile, start, end, action, char)
-
-		if err != nil {
-			restoreBackup(filename, backupFilename)
-			tmpFile.Close()
-			os.Remove(tmpFilename)
-			log.Fatalf("Errore: %v", err)
-		}
-
-		if err := file.Close(); err != nil {
-			restoreBackup(filename, backupFilename)
-			tmpFile.Close()
-			os.Remove(tmpFilename)
-			log.Fatalf("Errore: %v", err)
-		}
-
-		// Close the temporary file before renaming
-		if err := tmpFile.Close(); err != nil {
-			os.Remove(tmpFilename)
-			log.Fatalf("Errore: %v", err)
-		}
-
-		// Rename temporary file to original file
-		if err := os.Rename(tmpFilename, filename); err != nil {
-			restoreBackup(filename, backupFilename)
-			log.Fatalf("Errore: %v", err)
-		}
-
-		// Remove backup file after successful processing
-		os.Remove(backupFilename)
-	}
-}
-
-func createBackup(filename, backupFilename string) {
-	inputFile, err := os.Open(filename)
-	if err != nil {
-		log.Fatalf("Errore: %v", err)
-	}
-	defer inputFile.Close()
-
-	backupFile, err := os.Create(backupFilename)
-	if err != nil {
-		log.Fatalf("Errore: %v", err)
-	}
-	defer backupFile.Close()
-
-	_, err = io.Copy(backupFile, inputFile)
-	if err != nil {
-		log.Fatalf("Errore: %v", err)
-	}
-}
-
-func restoreBackup(filename, backupFilename string) {
-	// Remove the potentially corrupted file
-	os.Remove(filename)
-	// Restore the backup file
-	os.Rename(backupFilename, filename)
-}
-
-func FindLines(lineStr string) (startLine int, endLine int) {
-	if strings.Contains(lineStr, "-") {
-		parts := strings.Split(lineStr, "-")
-		if len(parts) != 2 {
-			log.Fatalf("invalid range format. Use 'start-end'")
-		}
-		startLine, err := strconv.Atoi(parts[0])
-		if err != nil || startLine <= 0 {
-			log.Fatalf("invalid start line number")
-		}
-		endLine, err = strconv.Atoi(parts[1])
-		if err != nil || endLine < startLine {
-			log.Fatalf("invalid end line number")
-		}
-		return startLine, endLine
-	} else {
-		startLine, err := strconv.Atoi(lineStr)
-		if err != nil || startLine <= 0 {
-			log.Fatalf("please provide a valid positive integer for the line number or a range")
-		}
-		endLine = startLine
-		return startLine, endLine
-	}
-}
-
-func writeChangesLine(inputFile *os.File, outputFile *os.File, start int, end int, action string, char string) error {
-	scanner := bufio.NewScanner(inputFile)
-	writer := bufio.NewWriter(outputFile)
-	
-	switch action{
-	case "comment":
-		currentLine := 1
-		for scanner.Scan() {
-			lineContent := scanner.Text()
-			if start <= currentLine && currentLine <= end {
-				lineContent = Comment(lineContent, char)
-			}
-			if _, err := writer.WriteString(lineContent + "\n"); err != nil {
-				return err
-			}
-			currentLine++
-		}
-		if end > currentLine {
-			return errors.New("line number is out of range")
-		}
-		if err := scanner.Err(); err != nil {
-			return err
-		}
-		return writer.Flush()
-	case "uncomment":
-		currentLine := 1
-		for scanner.Scan() {
-			lineContent := scanner.Text()
-			if start <= currentLine && currentLine <= end {
-				lineContent = Uncomment(lineContent, char)
-			}
-			if _, err := writer.WriteString(lineContent + "\n"); err != nil {
-				return err
-			}
-			currentLine++
-		}
-		if end > currentLine {
-			return errors.New("line number is out of range")
-		}
-		if err := scanner.Err(); err != nil {
-			return err
-		}
-		return writer.Flush()
-	case "toggle":
-		currentLine := 1
-		for scanner.Scan() {
-			lineContent := scanner.Text()
-			if start <= currentLine && currentLine <= end {
-				lineContent = ToggleComments(lineContent, char)
-			}
-			if _, err := writer.WriteString(lineContent + "\n"); err != nil {
-				return err
-			}
-			currentLine++
-		}
-		if end > currentLine {
-			return errors.New("line number is out of range")
-		}
-		if err := scanner.Err(); err != nil {
-			return err
-		}
-		return writer.Flush()
-	}
-	return errors.New("Action provided is not valid")
-}
-
-func writeChangesLabel(inputFile *os.File, outputFile *os.File, startLabel string, endLabel string, action string, char string) error {
-	scanner := bufio.NewScanner(inputFile)
-	writer := bufio.NewWriter(outputFile)
-	
-	switch action{
-	case "comment":
-		inSection := false
-		for scanner.Scan() {
-			lineContent := scanner.Text()
-			// HERE: if lineContent contain the start label, then fai currentLine++, se non contiene endLabel modifica
-			if strings.Contains(lineContent, endLabel){
-				inSection = false
-			}
-
-			if inSection {
-				lineContent = Comment(lineContent, char)
-			}
-
-			if strings.Contains(lineContent, startLabel){
-				inSection = true
-			}
-
-			if _, err := writer.WriteString(lineContent + "\n"); err != nil {
-				return err
-			}
-		}
-		if err := scanner.Err(); err != nil {
-			return err
-		}
-		return writer.Flush()
-	case "uncomment":
-		inSection := false
-		for scanner.Scan() {
-			lineContent := scanner.Text()
-			// HERE: if lineContent contain the start label, then fai currentLine++, se non contiene endLabel modifica
-			if strings.Contains(lineContent, endLabel){
-				inSection = false
-			}
-
-			if inSection {
-				lineContent = Uncomment(lineContent, char)
-			}
-
-			if strings.Contains(lineContent, startLabel){
-				inSection = true
-			}
-
-			if _, err := writer.WriteString(lineContent + "\n"); err != nil {
-				return err
-			}
-		}
-		if err := scanner.Err(); err != nil {
-			return err
-		}
-		return writer.Flush()
-	case "toggle":
-		inSection := false
-		for scanner.Scan() {
-			lineContent := scanner.Text()
-			// HERE: if lineContent contain the start label, then fai currentLine++, se non contiene endLabel modifica
-			if strings.Contains(lineContent, endLabel){
-				inSection = false
-			}
-
-			if inSection {
-				lineContent = ToggleComments(lineContent, char)
-			}
-
-			if strings.Contains(lineContent, startLabel){
-				inSection = true
-			}
-
-			if _, err := writer.WriteString(lineContent + "\n"); err != nil {
-				return err
-			}
-		}
-		if err := scanner.Err(); err != nil {
-			return err
-		}
-		return writer.Flush()
-	}
-	return errors.New("Action provided is not valid")
-}
-
-func Comment(line string, char string) string {
-	// just for html
-	if char == "<!-- -->" {
-		return "<!--" + " " + line + " " + "-->"
-	}
-	return char + " " + line
-}
-
-func Uncomment(line string, char string) string {
-	trimmedLine := strings.TrimSpace(line)
-
-	//just for html
-	if char == "<!-- -->" {
-		if strings.HasPrefix(trimmedLine, "<!--") && strings.HasSuffix(trimmedLine, "-->") {
-			// Check for both `<!--` and `<!-- ` prefixes.
-			if strings.HasPrefix(trimmedLine, "<!--"+" ") {
-				line = strings.Replace(line, "<!-- ", "", 1)
-				line = strings.Replace(line, "<!--", "", 1)
-			}
-
-			// Check for both '-->' and ' -->' suffixes
-			if strings.HasSuffix(trimmedLine, " "+"-->") {
-				line = strings.Replace(line, " "+"-->", "", 1)
-				line = strings.Replace(line, "-->", "", 1)
-			}
-
-			return line
-		}
-	}
-
-	if strings.HasPrefix(trimmedLine, char) {
-		// Check for both `//` and `// ` prefixes.
-		if strings.HasPrefix(trimmedLine, char + " ") {
-			return strings.Replace(line, char + " ", "", 1)
-		}
-		return strings.Replace(line, char, "", 1)
-	}
-	return line
-}
-
-func ToggleComments(line string, char string) string {
-	trimmedLine := strings.TrimSpace(line)
-
-	//just for html
-	if char == "<!-- -->" {
-		if strings.HasPrefix(trimmedLine, "<!--") && strings.HasSuffix(trimmedLine, "-->") {
-			return Uncomment(line, char)
-		}
-		return Comment(line, char)
-	}
-
-	if strings.HasPrefix(trimmedLine, char) {
-		return Uncomment(line, char)
-	} else {
-		return Comment(line, char)
-	}
-}
-
-func selectCommentChars(filename string) string {
-	extension := filepath.Ext(filename)
-	var commentChars string
-	switch extension {
-	case ".go":
-		commentChars = CommentChars["GoLang"]
-	case ".js":
-		commentChars = CommentChars["JS"]
-	case ".sh", ".bash":
-		commentChars = CommentChars["Bash"]
-	case ".cpp", ".cc", ".h", ".c":
-		commentChars = CommentChars["C++/C"]
-	case ".java":
-		commentChars = CommentChars["Java"]
-	case ".py":
-		commentChars = CommentChars["Pyhton"]
-	case ".rb":
-		commentChars = CommentChars["Ruby"]
-	case ".pl":
-		commentChars = CommentChars["Perl"]
-	case ".php":
-		commentChars = CommentChars["PHP"]
-	case ".swift":
-		commentChars = CommentChars["swift"]
-	case ".kt", ".kts":
-		commentChars = CommentChars["Kotlin"]
-	case ".R":
-		commentChars = CommentChars["R"]
-	case ".hs":
-		commentChars = CommentChars["Haskell"]
-	case ".sql":
-		commentChars = CommentChars["SQL"]
-	case ".rs":
-		commentChars = CommentChars["Rust"]
-	case ".scala":
-		commentChars = CommentChars["Scala"]
-	case ".dart":
-		commentChars = CommentChars["Dart"]
-	case ".mm":
-		commentChars = CommentChars["Objective-C"]
-	case ".m":
-		commentChars = CommentChars["MATLAB"]
-	case ".lua":
-		commentChars = CommentChars["Lua"]
-	case ".erl":
-		commentChars = CommentChars["Erlang"]
-	case ".ex", ".exs":
-		commentChars = CommentChars["Elixir"]
-	case ".ts":
-		commentChars = CommentChars["TS"]
-	case ".vhdl", ".vhd":
-		commentChars = CommentChars["VHDL"]
-	case ".v", ".sv":
-		commentChars = CommentChars["Verilog"]
-	case ".html":
-		commentChars = CommentChars["HTML"]
-	default:
-		fmt.Printf("unsupported file extension: %s", extension)
-		os.Exit(1)
-	}
-	return commentChars
-}
-
-var CommentChars = map[string]string{
-	"GoLang": "//",
-	"JS": "//",
-	"Bash":   "#",
-	"C++/C" : "//",
-	"Java" : "//",
-	"Pyhton" : "#",
-	"Ruby" : "#",
-	"Perl" : "#",
-	"PHP" : "//",
-	"Swift" : "//",
-	"Kotlin" : "//",
-	"R" : "#",
-	"Haskell" : "--",
-	"SQL" : "--",
-	"Rust" : "//",
-	"Scala" : "//",
-	"Dart" : "//",
-	"Objective-C" : "//",
-	"MATLAB" : "%",
-	"Lua" : "--",
-	"Erlang" : "%",
-	"Elixir" : "#",
-	"TS" : "//",
-	"VHDL" : "--",
-	"Verilog" : "//",
-	"HTML":        "<!-- -->",
+package utils
+
+import (
+    "bufio"
+    "fmt"
+    "log"
+	"strings"
+	"io"
+	"path/filepath"
+)
+
+/* phylosophy: gli input a queste funzioni devono essere tutti giusti! è nel file della flag che controlli se gli argumment delle flag sono
+giusti */
+
+/* This function is a copy of the previuous function but you use StartLabel and EndLabel instead of line as a string */
+func ChangeFileLabel(filename string, startLabel string, endLabel string, action string, dryrun bool){
+	if err := DefaultEngine.ChangeFileLabel(filename, startLabel, endLabel, Action(action), dryrun); err != nil {
+		log.Fatalf("%v", err)
+	}
+}
+
+/* same as the function ChangeFileLabel, but on the filesystem of the engine and returning the errors */
+func (e *Engine) ChangeFileLabel(filename string, startLabel string, endLabel string, action Action, dryrun bool) error {
+	if err := checkAction(action); err != nil {
+		return err
+	}
+	if _, err := CommentCharsFor(filename); err != nil {
+		return err
+	}
+	lines, err := e.readLines(filename)
+	if err != nil {
+		return ioErrorf("failed to open file: %w", err)
+	}
+	if !containsLabel(lines, startLabel) {
+		return noMatchErrorf("start label %q not found", startLabel)
+	}
+	logLanguage(filename)
+	// in html, vue, svelte, php and markdown files the comment characters depend on the region of each line
+	chars, err := lineCommentChars(filename, lines)
+	if err != nil {
+		return err
+	}
+	change := actionChange(filename, action)
+	logSelection(filename, lines, Selection{StartLabel: startLabel, EndLabel: endLabel})
+
+	if !dryrun {
+		return e.rewrite(filename, func(input io.Reader, output io.Writer) error {
+			return writeChangesLabel(input, output, startLabel, endLabel, change, chars)
+		})
+	}
+
+	// Open the file
+	file, err := e.FS.Open(filename)
+	if err != nil {
+		return ioErrorf("failed to open file: %w", err)
+	}
+	// Ensure file is closed at the end
+	defer file.Close()
+
+	// Create a new scanner for the file
+	scanner := bufio.NewScanner(file)
+	inSection := false
+	currentLine := 0
+	for scanner.Scan() {
+		lineContent := scanner.Text()
+		if strings.Contains(lineContent, endLabel){
+			inSection = false
+		}
+
+		if inSection {
+			fmt.Fprintln(e.Output, lineContent + " " + "->" + " " + change(lineContent, lineChars(chars, currentLine)))
+		}
+		currentLine++
+
+		if strings.Contains(lineContent, startLabel){
+			inSection = true
+		}
+	}
+	fmt.Fprint(e.Output, "\n\n")
+	// Check for scanning errors
+	if err := scanner.Err(); err != nil {
+		return ioErrorf("error reading file: %w", err)
+	}
+	return nil
+}
+
+/* Take in input the name of a file in the  current folder, a string that contains info about lines to be commented/uncommented, the action to do (comment,
+uncomment or toggle, if no argument is passed to the flag -a the defualt will be toggle) and dryrun. If true the modifications will be displayed on the
+terminal but will not be saved on the file. Otherwise the files will be modified */
+func ChangeFileLine(filename string, line string, action string, dryrun bool) {
+	if err := DefaultEngine.ChangeFileLine(filename, line, Action(action), dryrun); err != nil {
+		log.Fatalf("%v", err)
+	}
+}
+
+/* same as the function ChangeFileLine, but on the filesystem of the engine and returning the errors */
+func (e *Engine) ChangeFileLine(filename string, line string, action Action, dryrun bool) error {
+	if err := checkAction(action); err != nil {
+		return err
+	}
+	if _, err := CommentCharsFor(filename); err != nil {
+		return err
+	}
+
+	// find lines
+	start, end, err := ParseLines(line)
+	if err != nil {
+		return err
+	}
+	lines, err := e.readLines(filename)
+	if err != nil {
+		return ioErrorf("failed to open file: %w", err)
+	}
+	if end > len(lines) {
+		return noMatchErrorf("line number is out of range")
+	}
+	logLanguage(filename)
+	// in html, vue, svelte, php and markdown files the comment characters depend on the region of each line
+	chars, err := lineCommentChars(filename, lines)
+	if err != nil {
+		return err
+	}
+	change := actionChange(filename, action)
+	logSelection(filename, lines, Selection{Lines: line})
+
+	if !dryrun {
+		return e.rewrite(filename, func(input io.Reader, output io.Writer) error {
+			return writeChangesLine(input, output, start, end, change, chars)
+		})
+	}
+
+	// Open the file
+	file, err := e.FS.Open(filename)
+	if err != nil {
+		return ioErrorf("failed to open file: %w", err)
+	}
+	// Ensure file is closed at the end
+	defer file.Close()
+
+	// Create a new scanner for the file
+	scanner := bufio.NewScanner(file)
+	currentLine := 1
+	for scanner.Scan() {
+		lineContent := scanner.Text()
+		if start <= currentLine && currentLine <= end {
+			fmt.Fprintln(e.Output, lineContent + " " + "->" + " " + change(lineContent, lineChars(chars, currentLine-1)))
+		} else {
+			fmt.Fprintln(e.Output, lineContent)
+		}
+		currentLine++
+	}
+	fmt.Fprint(e.Output, "\n\n")
+	// Check for scanning errors
+	if err := scanner.Err(); err != nil {
+		return ioErrorf("error reading file: %w", err)
+	}
+	return nil
+}
+
+/* returns true if a line contains the label */
+func containsLabel(lines []string, label string) bool {
+	for _, lineContent := range lines {
+		if strings.Contains(lineContent, label) {
+			return true
+		}
+	}
+	return false
+}
+
+func FindLines(lineStr string) (startLine int, endLine int) {
+	startLine, endLine, err := ParseLines(lineStr)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	return startLine, endLine
+}
+
+/* returns the comment characters of the line (0-based), or the ones of the last line if the file has grown since
+chars was computed */
+func lineChars(chars []string, line int) string {
+	if line < len(chars) {
+		return chars[line]
+	}
+	if len(chars) == 0 {
+		return ""
+	}
+	return chars[len(chars)-1]
+}
+
+/* writes the input to output with change applied to the lines from start to end, each one with its own comment
+characters */
+func writeChangesLine(input io.Reader, output io.Writer, start int, end int, change func(string, string) string, chars []string) error {
+	scanner := bufio.NewScanner(input)
+	writer := bufio.NewWriter(output)
+
+	currentLine := 1
+	for scanner.Scan() {
+		lineContent := scanner.Text()
+		if start <= currentLine && currentLine <= end {
+			lineContent = change(lineContent, lineChars(chars, currentLine-1))
+		}
+		if _, err := writer.WriteString(lineContent + "\n"); err != nil {
+			return err
+		}
+		currentLine++
+	}
+	if end > currentLine {
+		return noMatchErrorf("line number is out of range")
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+/* writes the input to output with change applied to the lines between startLabel and endLabel, each one with its own
+comment characters */
+func writeChangesLabel(input io.Reader, output io.Writer, startLabel string, endLabel string, change func(string, string) string, chars []string) error {
+	scanner := bufio.NewScanner(input)
+	writer := bufio.NewWriter(output)
+
+	inSection := false
+	currentLine := 0
+	for scanner.Scan() {
+		lineContent := scanner.Text()
+		if strings.Contains(lineContent, endLabel){
+			inSection = false
+		}
+
+		if inSection {
+			lineContent = change(lineContent, lineChars(chars, currentLine))
+		}
+		currentLine++
+
+		if strings.Contains(lineContent, startLabel){
+			inSection = true
+		}
+
+		if _, err := writer.WriteString(lineContent + "\n"); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+/* Comment characters made of two parts separated by a space (e.g. "<!-- -->" for html, or the ones of css) are block
+comments: the line is wrapped between the opening and the closing part instead of being prefixed */
+func blockCommentChars(char string) (open string, close string, ok bool) {
+	return strings.Cut(char, " ")
+}
+
+func Comment(line string, char string) string {
+	// block comments like html and css
+	if open, close, ok := blockCommentChars(char); ok {
+		return open + " " + line + " " + close
+	}
+	return char + " " + line
+}
+
+/* removes the comment characters added by Comment, so that Uncomment(Comment(line, char), char) is always line. Lines
+that are not commented are returned as they are */
+func Uncomment(line string, char string) string {
+	if indent, body, trailing, ok := commentedBody(line, char); ok {
+		return indent + body + trailing
+	}
+	return line
+}
+
+func ToggleComments(line string, char string) string {
+	if IsCommented(line, char) {
+		return Uncomment(line, char)
+	}
+	return Comment(line, char)
+}
+
+/* splits the line in its indentation (spaces and tabs) and the rest */
+func splitIndent(line string) (indent string, rest string) {
+	rest = strings.TrimLeft(line, " \t")
+	return line[:len(line)-len(rest)], rest
+}
+
+/* if the line is commented with char returns its indentation, the commented text and, for block comments, the spaces
+after the closing characters. The space written by Comment after the opening characters (and before the closing ones)
+is part of the comment, so a blank line commented as "// " goes back to an empty line */
+func commentedBody(line string, char string) (indent string, body string, trailing string, ok bool) {
+	indent, rest := splitIndent(line)
+	// block comments like html and css
+	if open, close, isBlock := blockCommentChars(char); isBlock {
+		trimmed := strings.TrimRight(rest, " \t\r")
+		if len(trimmed) < len(open)+len(close) || !strings.HasPrefix(trimmed, open) || !strings.HasSuffix(trimmed, close) {
+			return "", "", "", false
+		}
+		body = trimmed[len(open) : len(trimmed)-len(close)]
+		body = strings.TrimPrefix(body, " ")
+		body = strings.TrimSuffix(body, " ")
+		return indent, body, rest[len(trimmed):], true
+	}
+	if !strings.HasPrefix(rest, char) {
+		return "", "", "", false
+	}
+	return indent, strings.TrimPrefix(rest[len(char):], " "), "", true
+}
+
+/* returns the comment characters of the language of the file, chosen with its extension */
+func CommentCharsFor(filename string) (string, error) {
+	language, err := LanguageFor(filename)
+	if err != nil {
+		return "", err
+	}
+	return language.CommentChars(), nil
+}
+
+/* returns the language of a file from its extension, the language is a key of CommentChars */
+func LanguageFor(filename string) (Language, error) {
+	extension := filepath.Ext(filename)
+	// .env files are often called .env.local, .env.production and so on
+	if strings.HasPrefix(filepath.Base(filename), ".env") {
+		extension = ".env"
+	}
+	language, ok := extensionLanguages[extension]
+	if !ok {
+		return "", usageErrorf("unsupported file extension: %s", extension)
+	}
+	return language, nil
+}
+
+/* languages of the supported file extensions */
+var extensionLanguages = map[string]Language{
+	".go": "GoLang",
+	".js": "JS",
+	".sh": "Bash",
+	".bash": "Bash",
+	".cpp": "C++/C",
+	".cc": "C++/C",
+	".h": "C++/C",
+	".c": "C++/C",
+	".java": "Java",
+	".py": "Pyhton",
+	".rb": "Ruby",
+	".pl": "Perl",
+	".php": "PHP",
+	".swift": "Swift",
+	".kt": "Kotlin",
+	".kts": "Kotlin",
+	".R": "R",
+	".hs": "Haskell",
+	".sql": "SQL",
+	".rs": "Rust",
+	".scala": "Scala",
+	".dart": "Dart",
+	".mm": "Objective-C",
+	".m": "MATLAB",
+	".lua": "Lua",
+	".erl": "Erlang",
+	".ex": "Elixir",
+	".exs": "Elixir",
+	".ts": "TS",
+	".vhdl": "VHDL",
+	".vhd": "VHDL",
+	".v": "Verilog",
+	".sv": "Verilog",
+	".html": "HTML",
+	".htm": "HTML",
+	".vue": "HTML",
+	".svelte": "HTML",
+	".css": "CSS",
+	".md": "HTML",
+	".markdown": "HTML",
+	".rst": "HTML",
+	".yaml": "YAML",
+	".yml": "YAML",
+	".toml": "TOML",
+	".ini": "INI",
+	".cfg": "INI",
+	".conf": "INI",
+	".env": "Env",
+	".jsonc": "JSONC",
+	".json5": "JSONC",
+}
+
+var CommentChars = map[string]string{
+	"GoLang": "//",
+	"JS": "//",
+	"Bash":   "#",
+	"C++/C" : "//",
+	"Java" : "//",
+	"Pyhton" : "#",
+	"Ruby" : "#",
+	"Perl" : "#",
+	"PHP" : "//",
+	"Swift" : "//",
+	"Kotlin" : "//",
+	"R" : "#",
+	"Haskell" : "--",
+	"SQL" : "--",
+	"Rust" : "//",
+	"Scala" : "//",
+	"Dart" : "//",
+	"Objective-C" : "//",
+	"MATLAB" : "%",
+	"Lua" : "--",
+	"Erlang" : "%",
+	"Elixir" : "#",
+	"TS" : "//",
+	"VHDL" : "--",
+	"Verilog" : "//",
+	"HTML":        "<!-- -->",
+	"CSS": "/* */",
+	"YAML": "#",
+	"TOML": "#",
+	"INI": ";",
+	"Env": "#",
+	"JSONC": "//",
 }
\ No newline at end of file
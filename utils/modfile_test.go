@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+/* the comment characters of every language, each one once */
+func fuzzCommentChars() []string {
+	seen := map[string]bool{}
+	var chars []string
+	for _, char := range CommentChars {
+		if !seen[char] {
+			seen[char] = true
+			chars = append(chars, char)
+		}
+	}
+	sort.Strings(chars)
+	return chars
+}
+
+func addCommentSeeds(f *testing.F) {
+	for _, line := range []string{
+		"", " ", "\t", "  \t ", "x := 1", "    return x", "\tif x {", "// x", "//x", "# x", "#", "<!-- x -->", "<!--x-->",
+		"  <!-- <p>text</p> -->", "<!---->", "<!-->", "/* a */", "/**/", "/*/", "a -->", "-- a", "% a", "; a", "a\r",
+		"<!-- a -->  ", " // ", " x",
+	} {
+		f.Add(line)
+	}
+}
+
+/* Uncomment(Comment(x)) is x, the commented line is recognized as commented and toggling twice gives back x */
+func FuzzCommentRoundTrip(f *testing.F) {
+	addCommentSeeds(f)
+	f.Fuzz(func(t *testing.T, line string) {
+		if strings.Contains(line, "\n") {
+			t.Skip()
+		}
+		for _, char := range fuzzCommentChars() {
+			commented := Comment(line, char)
+			if !IsCommented(commented, char) {
+				t.Errorf("%q: Comment gives %q, that is not commented", char, commented)
+			}
+			if got := Uncomment(commented, char); got != line {
+				t.Errorf("%q: Uncomment(Comment(%q)) = %q", char, line, got)
+			}
+			if got := ToggleComments(commented, char); got != line {
+				t.Errorf("%q: ToggleComments(Comment(%q)) = %q", char, line, got)
+			}
+			if !IsCommented(line, char) {
+				if got := ToggleComments(ToggleComments(line, char), char); got != line {
+					t.Errorf("%q: toggling %q twice gives %q", char, line, got)
+				}
+			}
+		}
+	})
+}
+
+/* Uncomment does not change a line that is not commented, and commenting again an uncommented line gives the same
+line: the actions can be repeated without changing the result */
+func FuzzCommentIdempotence(f *testing.F) {
+	addCommentSeeds(f)
+	f.Fuzz(func(t *testing.T, line string) {
+		if strings.Contains(line, "\n") {
+			t.Skip()
+		}
+		for _, char := range fuzzCommentChars() {
+			if !IsCommented(line, char) {
+				if got := Uncomment(line, char); got != line {
+					t.Errorf("%q: Uncomment(%q) = %q, but the line is not commented", char, line, got)
+				}
+			} else if got := Comment(Uncomment(line, char), char); !IsCommented(got, char) {
+				t.Errorf("%q: Comment(Uncomment(%q)) = %q is not commented", char, line, got)
+			}
+			commented := Comment(line, char)
+			if got := Comment(Uncomment(commented, char), char); got != commented {
+				t.Errorf("%q: Comment(Uncomment(%q)) = %q", char, commented, got)
+			}
+			if open, _, _ := strings.Cut(char, " "); !strings.HasPrefix(commented, open) {
+				t.Errorf("%q: Comment(%q) = %q does not start with the comment characters", char, line, commented)
+			}
+		}
+	})
+}
+
+/* FindLines is ParseLines that exits on errors, so the fuzzer checks ParseLines: the lines of a valid range are
+positive and ordered, and the range written as "start-end" gives the same lines */
+func FuzzFindLines(f *testing.F) {
+	for _, lines := range []string{"1", "3-5", "5-3", "0", "-1", "1-", "-", "1-2-3", "+2", "2-+4", " 1", "99999999999999999999", "a-b"} {
+		f.Add(lines)
+	}
+	f.Fuzz(func(t *testing.T, lines string) {
+		start, end, err := ParseLines(lines)
+		if err != nil {
+			return
+		}
+		if start < 1 || end < start {
+			t.Fatalf("ParseLines(%q) = %d, %d", lines, start, end)
+		}
+		if !strings.Contains(lines, "-") && start != end {
+			t.Errorf("ParseLines(%q) = %d, %d, want a single line", lines, start, end)
+		}
+		canonical := fmt.Sprintf("%d-%d", start, end)
+		if gotStart, gotEnd, err := ParseLines(canonical); err != nil || gotStart != start || gotEnd != end {
+			t.Errorf("ParseLines(%q) = %d, %d, %v, want %d, %d", canonical, gotStart, gotEnd, err, start, end)
+		}
+	})
+}
@@ -0,0 +1,336 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+/* Jupyter notebooks are json documents whose cells contain the code as a "source" string or list of strings. To keep
+all the other fields of the notebook (and the way it is formatted) untouched, the notebook is never encoded again:
+only the string literals of the source of the selected cells are replaced in the original bytes */
+
+/* NotebookSelection tells which lines of a notebook must be modified. Cells is a cell index or a range of cells
+(1-based, like lines), Tag selects the cells with that tag in metadata.tags. Lines and the labels select lines inside
+the selected cells; if none of them is given the whole source of the selected cells is modified */
+type NotebookSelection struct {
+	Cells      string
+	Tag        string
+	Lines      string
+	StartLabel string
+	EndLabel   string
+}
+
+type notebookCell struct {
+	CellType string `json:"cell_type"`
+	Metadata struct {
+		Tags []string `json:"tags"`
+	} `json:"metadata"`
+}
+
+type notebook struct {
+	Cells    []notebookCell `json:"cells"`
+	Metadata struct {
+		Kernelspec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+}
+
+/* returns true if the file is a jupyter notebook */
+func IsNotebook(filename string) bool {
+	return filepath.Ext(filename) == ".ipynb"
+}
+
+/* Take in input the name of a notebook, the cells and lines to modify, the action (comment, uncomment or toggle) and
+dryrun. Code cells are commented with the language of the kernel, markdown cells with html comments and raw cells are
+never modified. The errors are returned, as by the Engine method */
+func ChangeNotebook(filename string, selection NotebookSelection, action string, dryrun bool) error {
+	return DefaultEngine.ChangeNotebook(filename, selection, Action(action), dryrun)
+}
+
+/* same as the function ChangeNotebook, but on the filesystem of the engine and returning the errors. As for the other
+files the new notebook is written in a temporary file, with a backup made as the backup policy says */
+func (e *Engine) ChangeNotebook(filename string, selection NotebookSelection, action Action, dryrun bool) error {
+	if err := checkAction(action); err != nil {
+		return err
+	}
+	data, err := fs.ReadFile(e.FS, filename)
+	if err != nil {
+		return ioErrorf("failed to open file: %w", err)
+	}
+
+	output, changes, err := changeNotebookSource(data, selection, action)
+	if err != nil {
+		return err
+	}
+
+	if dryrun {
+		for _, change := range changes {
+			fmt.Fprintln(e.Output, change)
+		}
+		fmt.Fprint(e.Output, "\n\n")
+		return nil
+	}
+	return e.rewrite(filename, func(_ io.Reader, w io.Writer) error {
+		_, err := w.Write(output)
+		return err
+	})
+}
+
+/* returns the modified notebook and, for the dry run, the description of every modified line */
+func changeNotebookSource(data []byte, selection NotebookSelection, action Action) ([]byte, []string, error) {
+	var nb notebook
+	if err := json.Unmarshal(data, &nb); err != nil {
+		return nil, nil, fmt.Errorf("invalid notebook: %v", err)
+	}
+	spans, err := notebookSourceSpans(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid notebook: %v", err)
+	}
+	if len(spans) != len(nb.Cells) {
+		return nil, nil, errors.New("invalid notebook: cannot find the source of every cell")
+	}
+
+	kernelLanguage := nb.Metadata.Kernelspec.Language
+	if kernelLanguage == "" {
+		kernelLanguage = nb.Metadata.LanguageInfo.Name
+	}
+	codeChars, ok := CommentChars[fenceLanguages[strings.ToLower(kernelLanguage)]]
+	if !ok {
+		return nil, nil, usageErrorf("unsupported notebook language: %q", kernelLanguage)
+	}
+
+	firstCell, lastCell := 1, len(nb.Cells)
+	if selection.Cells != "" {
+		var err error
+		if firstCell, lastCell, err = ParseLines(selection.Cells); err != nil {
+			return nil, nil, err
+		}
+		if lastCell > len(nb.Cells) {
+			return nil, nil, noMatchErrorf("cell number is out of range")
+		}
+	}
+	startLine, endLine := 1, -1
+	if selection.Lines != "" {
+		var err error
+		if startLine, endLine, err = ParseLines(selection.Lines); err != nil {
+			return nil, nil, err
+		}
+	}
+	withLabels := selection.StartLabel != "" && selection.EndLabel != ""
+
+	var output bytes.Buffer
+	var changes []string
+	last := 0
+	for i, cell := range nb.Cells {
+		if i+1 < firstCell || i+1 > lastCell || (selection.Tag != "" && !hasTag(cell.Metadata.Tags, selection.Tag)) {
+			continue
+		}
+		var char string
+		switch cell.CellType {
+		case "code":
+			char = codeChars
+		case "markdown":
+			char = CommentChars["HTML"]
+		default:
+			continue
+		}
+
+		currentLine := 1
+		inSection := false
+		changeLine := func(lineContent string) string {
+			selected := true
+			if selection.Lines != "" {
+				selected = startLine <= currentLine && (endLine < 0 || currentLine <= endLine)
+			}
+			if withLabels {
+				if strings.Contains(lineContent, selection.EndLabel) {
+					inSection = false
+				}
+				selected = selected && inSection
+				if strings.Contains(lineContent, selection.StartLabel) {
+					inSection = true
+				}
+			}
+			currentLine++
+			if !selected {
+				return lineContent
+			}
+			newContent := applyAction(lineContent, char, action)
+			changes = append(changes, fmt.Sprintf("cell %d: %s -> %s", i+1, lineContent, newContent))
+			return newContent
+		}
+
+		source, err := changeSourceValue(data[spans[i][0]:spans[i][1]], changeLine)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid source in cell %d: %v", i+1, err)
+		}
+		output.Write(data[last:spans[i][0]])
+		output.Write(source)
+		last = spans[i][1]
+	}
+	output.Write(data[last:])
+	return output.Bytes(), changes, nil
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+/* applies the action to a single line, the actions are the same of the -a flag. The callers check the action with
+checkAction before changing any line, an unknown action leaves the line as it is */
+func applyAction(line string, char string, action Action) string {
+	switch action {
+	case ActionComment:
+		return Comment(line, char)
+	case ActionUncomment:
+		return Uncomment(line, char)
+	case ActionToggle:
+		return ToggleComments(line, char)
+	}
+	return line
+}
+
+/* returns the start and end offsets in data of the "source" value of every cell of the notebook, in the order of the
+cells. The notebook is read token by token so that the offsets refer to the original bytes */
+func notebookSourceSpans(data []byte) ([][2]int, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	expectDelim := func(delim json.Delim) error {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if d, ok := tok.(json.Delim); !ok || d != delim {
+			return fmt.Errorf("expected %v", delim)
+		}
+		return nil
+	}
+	skipValue := func() error {
+		var raw json.RawMessage
+		return dec.Decode(&raw)
+	}
+
+	var spans [][2]int
+	if err := expectDelim('{'); err != nil {
+		return nil, err
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if key != "cells" {
+			if err := skipValue(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := expectDelim('['); err != nil {
+			return nil, err
+		}
+		for dec.More() {
+			if err := expectDelim('{'); err != nil {
+				return nil, err
+			}
+			span := [2]int{-1, -1}
+			for dec.More() {
+				field, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				var raw json.RawMessage
+				if err := dec.Decode(&raw); err != nil {
+					return nil, err
+				}
+				if field == "source" {
+					end := int(dec.InputOffset())
+					span = [2]int{end - len(raw), end}
+				}
+			}
+			if err := expectDelim('}'); err != nil {
+				return nil, err
+			}
+			if span[0] < 0 {
+				return nil, errors.New("cell without source")
+			}
+			spans = append(spans, span)
+		}
+		if err := expectDelim(']'); err != nil {
+			return nil, err
+		}
+	}
+	return spans, nil
+}
+
+/* applies changeLine to every line of a source value, that is either a json string or a list of json strings. Only
+the string literals are replaced, the whitespace between them is kept as it is */
+func changeSourceValue(raw []byte, changeLine func(string) string) ([]byte, error) {
+	var out bytes.Buffer
+	pos := 0
+	for pos < len(raw) {
+		if raw[pos] != '"' {
+			out.WriteByte(raw[pos])
+			pos++
+			continue
+		}
+		end := stringLiteralEnd(raw, pos)
+		if end < 0 {
+			return nil, errors.New("unterminated string")
+		}
+		var text string
+		if err := json.Unmarshal(raw[pos:end], &text); err != nil {
+			return nil, err
+		}
+		lines := strings.Split(text, "\n")
+		for i := range lines {
+			// the empty string after the final newline is not a line
+			if i == len(lines)-1 && lines[i] == "" && i > 0 {
+				break
+			}
+			lines[i] = changeLine(lines[i])
+		}
+		if newText := strings.Join(lines, "\n"); newText != text {
+			out.Write(encodeJSONString(newText))
+		} else {
+			out.Write(raw[pos:end])
+		}
+		pos = end
+	}
+	return out.Bytes(), nil
+}
+
+/* returns the offset after the closing quote of the string literal starting at start, or -1 */
+func stringLiteralEnd(raw []byte, start int) int {
+	for i := start + 1; i < len(raw); i++ {
+		switch raw[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+/* encodes a string the way jupyter does, without escaping html characters */
+func encodeJSONString(s string) []byte {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
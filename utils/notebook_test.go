@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+/* a notebook formatted as jupyter writes it, with a source as list and one as string */
+const testNotebook = `{
+ "cells": [
+  {
+   "cell_type": "code",
+   "execution_count": 1,
+   "metadata": {"tags": ["debug"]},
+   "outputs": [],
+   "source": [
+    "x = 1\n",
+    "print(\"x = \\\"1\\\"\")"
+   ]
+  },
+  {
+   "cell_type": "markdown",
+   "metadata": {},
+   "source": "# Title\nsome text"
+  },
+  {
+   "cell_type": "raw",
+   "metadata": {},
+   "source": ["raw text"]
+  },
+  {
+   "cell_type": "code",
+   "execution_count": null,
+   "metadata": {},
+   "outputs": [],
+   "source": ["y = 2\n", "# z = 3"]
+  }
+ ],
+ "metadata": {"kernelspec": {"language": "python", "name": "python3"}},
+ "nbformat": 4,
+ "nbformat_minor": 5
+}
+`
+
+func TestChangeNotebook(t *testing.T) {
+	tests := []struct {
+		name      string
+		selection NotebookSelection
+		action    Action
+		replace   []string // pairs of old and new text of the notebook
+	}{
+		{"one cell", NotebookSelection{Cells: "1"}, ActionComment, []string{
+			`"x = 1\n"`, `"# x = 1\n"`,
+			`"print(\"x = \\\"1\\\"\")"`, `"# print(\"x = \\\"1\\\"\")"`,
+		}},
+		{"markdown cell", NotebookSelection{Cells: "2"}, ActionComment, []string{
+			`"# Title\nsome text"`, `"<!-- # Title -->\n<!-- some text -->"`,
+		}},
+		{"raw cell", NotebookSelection{Cells: "3"}, ActionComment, nil},
+		{"tag", NotebookSelection{Tag: "debug", Lines: "2"}, ActionComment, []string{
+			`"print(\"x = \\\"1\\\"\")"`, `"# print(\"x = \\\"1\\\"\")"`,
+		}},
+		{"lines of every cell", NotebookSelection{Cells: "3-4", Lines: "2"}, ActionToggle, []string{
+			`"# z = 3"`, `"z = 3"`,
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			memfs := NewMemFS(map[string]string{"nb.ipynb": testNotebook})
+			engine := &Engine{FS: memfs, Output: &bytes.Buffer{}}
+			if err := engine.ChangeNotebook("nb.ipynb", test.selection, test.action, false); err != nil {
+				t.Fatal(err)
+			}
+			want := strings.NewReplacer(test.replace...).Replace(testNotebook)
+			if got, _ := memfs.ReadFile("nb.ipynb"); string(got) != want {
+				t.Errorf("notebook\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestChangeNotebookDryRun(t *testing.T) {
+	memfs := NewMemFS(map[string]string{"nb.ipynb": testNotebook})
+	var output bytes.Buffer
+	engine := &Engine{FS: memfs, Output: &output}
+	if err := engine.ChangeNotebook("nb.ipynb", NotebookSelection{Cells: "4"}, ActionToggle, true); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := memfs.ReadFile("nb.ipynb"); string(got) != testNotebook {
+		t.Error("dry run modified the notebook")
+	}
+	want := "cell 4: y = 2 -> # y = 2\ncell 4: # z = 3 -> z = 3\n\n\n"
+	if output.String() != want {
+		t.Errorf("dry run printed %q, want %q", output.String(), want)
+	}
+}
+
+func TestChangeNotebookErrors(t *testing.T) {
+	var usage *UsageError
+	var noMatch *NoMatchError
+	memfs := NewMemFS(map[string]string{
+		"nb.ipynb":      testNotebook,
+		"cobol.ipynb":   strings.Replace(testNotebook, `"language": "python"`, `"language": "cobol"`, 1),
+		"invalid.ipynb": `{"cells": [`,
+	})
+	engine := &Engine{FS: memfs, Output: &bytes.Buffer{}}
+	if err := engine.ChangeNotebook("nb.ipynb", NotebookSelection{Cells: "5"}, ActionComment, false); !errors.As(err, &noMatch) {
+		t.Errorf("cell out of range: %v, want a NoMatchError", err)
+	}
+	if err := engine.ChangeNotebook("cobol.ipynb", NotebookSelection{Cells: "1"}, ActionComment, false); !errors.As(err, &usage) {
+		t.Errorf("unsupported language: %v, want a UsageError", err)
+	}
+	if err := engine.ChangeNotebook("invalid.ipynb", NotebookSelection{Cells: "1"}, ActionComment, false); err == nil {
+		t.Error("no error for an invalid notebook")
+	}
+	if names := memfs.Names(); len(names) != 3 {
+		t.Errorf("files left after the errors: %v", names)
+	}
+}
@@ -0,0 +1,245 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+/* Picker is the state of tgcom pick, that shows a file in the terminal and lets the user select the lines to modify
+with the keyboard instead of counting them for -l. The keys are given to HandleKey by name ("up", "down", "pgup",
+"pgdown", "home", "end", "tab", "enter", "esc") or as the typed character, and Render draws the screen. Commented
+lines are dimmed, labels are highlighted and the selection is shown in reverse video; in preview the selected lines
+are shown as they will be after the action */
+type Picker struct {
+	File   string
+	Action Action
+
+	lines   []string
+	chars   []string
+	blocks  []Block
+	cursor  int // line of the cursor, 0-based
+	anchor  int // other end of the selection, -1 if only the line of the cursor is selected
+	top     int // first line on the screen
+	height  int // lines of the file on the screen, set by Render
+	preview bool
+	message string
+	done    bool
+	apply   bool
+}
+
+/* ANSI escape sequences used by Render */
+const (
+	ansiReset   = "\x1b[0m"
+	ansiReverse = "\x1b[7m"
+	ansiDim     = "\x1b[2m"
+	ansiLabel   = "\x1b[1;36m"
+	ansiChanged = "\x1b[33m"
+	ansiGutter  = "\x1b[90m"
+	ansiClear   = "\x1b[H\x1b[2J"
+)
+
+/* reads the file and returns a picker with the cursor on the first line */
+func NewPicker(filename string) (*Picker, error) {
+	lines, err := readFileLines(filename)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("%s is empty", filename)
+	}
+	chars, err := lineCommentChars(filename, lines)
+	if err != nil {
+		return nil, err
+	}
+	blocks, _ := FindBlocks(filename, lines, "", "")
+	return &Picker{File: filename, Action: ActionToggle, lines: lines, chars: chars, blocks: blocks, anchor: -1, height: 20}, nil
+}
+
+/* returns true when the user has finished, and apply is true if the action must be applied to the selection */
+func (p *Picker) Done() (finished bool, apply bool) {
+	return p.done, p.apply
+}
+
+/* returns the selected lines, 1-based, in the syntax of the -l flag */
+func (p *Picker) Lines() string {
+	start, end := p.selection()
+	if start == end {
+		return fmt.Sprint(start + 1)
+	}
+	return fmt.Sprintf("%d-%d", start+1, end+1)
+}
+
+/* returns the first and the last selected line, 0-based */
+func (p *Picker) selection() (int, int) {
+	if p.anchor < 0 {
+		return p.cursor, p.cursor
+	}
+	if p.anchor < p.cursor {
+		return p.anchor, p.cursor
+	}
+	return p.cursor, p.anchor
+}
+
+/* applies the action to the selected lines of the file and returns the number of lines modified */
+func (p *Picker) Apply() (int, error) {
+	return ChangeFileSelection(p.File, Selection{Lines: p.Lines()}, p.Action, "", false)
+}
+
+/* updates the state after a key has been pressed */
+func (p *Picker) HandleKey(key string) {
+	p.message = ""
+	switch key {
+	case "up", "k":
+		p.move(-1)
+	case "down", "j":
+		p.move(1)
+	case "pgup":
+		p.move(-p.height)
+	case "pgdown":
+		p.move(p.height)
+	case "home", "g":
+		p.move(-len(p.lines))
+	case "end", "G":
+		p.move(len(p.lines))
+	case " ", "v":
+		// starts a range at the cursor, or goes back to the line of the cursor
+		if p.anchor < 0 {
+			p.anchor = p.cursor
+		} else {
+			p.anchor = -1
+		}
+	case "tab":
+		p.nextBlock()
+	case "b":
+		p.selectBlock()
+	case "c":
+		p.Action = ActionComment
+	case "u":
+		p.Action = ActionUncomment
+	case "t":
+		p.Action = ActionToggle
+	case "p":
+		p.preview = !p.preview
+	case "enter":
+		p.done, p.apply = true, true
+	case "q", "esc", "ctrl-c":
+		p.done = true
+	}
+}
+
+/* moves the cursor by delta lines, scrolling the screen if needed */
+func (p *Picker) move(delta int) {
+	p.cursor += delta
+	if p.cursor < 0 {
+		p.cursor = 0
+	}
+	if p.cursor >= len(p.lines) {
+		p.cursor = len(p.lines) - 1
+	}
+}
+
+/* moves the cursor on the start label of the next block, going back to the first one after the last */
+func (p *Picker) nextBlock() {
+	if len(p.blocks) == 0 {
+		p.message = "no labelled blocks in this file"
+		return
+	}
+	p.anchor = -1
+	for _, block := range p.blocks {
+		if block.Start-1 > p.cursor {
+			p.cursor = block.Start - 1
+			return
+		}
+	}
+	p.cursor = p.blocks[0].Start - 1
+}
+
+/* selects the lines between the labels of the innermost block that contains the cursor */
+func (p *Picker) selectBlock() {
+	var found *Block
+	for i, block := range p.blocks {
+		if block.Start-1 <= p.cursor && p.cursor <= block.End-1 && (found == nil || block.Start > found.Start) {
+			found = &p.blocks[i]
+		}
+	}
+	if found == nil {
+		p.message = "the cursor is not inside a labelled block (tab goes to the next one)"
+		return
+	}
+	if found.End-found.Start < 2 {
+		p.message = fmt.Sprintf("block %q is empty", found.Name)
+		return
+	}
+	p.anchor, p.cursor = found.Start, found.End-2
+	p.message = fmt.Sprintf("block %q (%s)", found.Name, found.State)
+}
+
+/* draws the screen for a terminal of the given size: the lines of the file around the cursor and two status lines */
+func (p *Picker) Render(width int, height int) string {
+	p.height = height - 2
+	if p.height < 1 {
+		p.height = 1
+	}
+	if p.cursor < p.top {
+		p.top = p.cursor
+	}
+	if p.cursor >= p.top+p.height {
+		p.top = p.cursor - p.height + 1
+	}
+
+	start, end := p.selection()
+	var newLines []string
+	if p.preview {
+		newLines, _ = ChangeLines(p.File, p.lines, Selection{Lines: p.Lines()}, p.Action, "")
+	}
+
+	gutter := len(fmt.Sprint(len(p.lines)))
+	var b strings.Builder
+	b.WriteString(ansiClear)
+	for i := p.top; i < p.top+p.height && i < len(p.lines); i++ {
+		lineContent := p.lines[i]
+		style := ""
+		switch {
+		case startLabelRegexp.MatchString(lineContent) || endLabelRegexp.MatchString(lineContent):
+			style = ansiLabel
+		case newLines != nil && newLines[i] != lineContent:
+			lineContent = newLines[i]
+			style = ansiChanged
+		case p.chars[i] != "" && strings.TrimSpace(lineContent) != "" && IsCommented(lineContent, p.chars[i]):
+			style = ansiDim
+		}
+		if i >= start && i <= end {
+			style += ansiReverse
+		}
+		marker := " "
+		if i == p.cursor {
+			marker = ">"
+		}
+		fmt.Fprintf(&b, "%s%s%*d%s %s%s%s\r\n", marker, ansiGutter, gutter, i+1, ansiReset, style, fitWidth(lineContent, width-gutter-2), ansiReset)
+	}
+
+	mode := ""
+	if p.preview {
+		mode = "  [preview]"
+	}
+	status := fmt.Sprintf("%s  lines %s  action %s%s", p.File, p.Lines(), p.Action, mode)
+	if p.message != "" {
+		status += "  " + p.message
+	}
+	fmt.Fprintf(&b, "%s%s%s\r\n", ansiReverse, fitWidth(status, width), ansiReset)
+	b.WriteString(fitWidth("↑↓ move  space range  b block  tab next block  c/u/t action  p preview  enter apply  q cancel", width))
+	return b.String()
+}
+
+/* expands the tabs and cuts s to width characters */
+func fitWidth(s string, width int) string {
+	s = strings.ReplaceAll(s, "\t", "    ")
+	if width < 1 {
+		return ""
+	}
+	runes := []rune(s)
+	if len(runes) > width {
+		return string(runes[:width])
+	}
+	return s
+}
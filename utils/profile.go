@@ -0,0 +1,138 @@
+package utils
+
+import (
+	"fmt"
+)
+
+/* A profile is a feature switch that spans many files, like "debug mode" or "mock backend": it is made of named blocks
+that are uncommented when the profile is enabled and commented when it is disabled. Blocks with invert are the
+opposite: they are commented when the profile is enabled (e.g. the real backend when the mock one is enabled).
+Profiles are declared in the project configuration file:
+
+	profiles:
+	  mock-backend:
+	    - files: ["internal/api/*.go"]
+	      block: mock
+	    - files: ["internal/api/client.go"]
+	      block: real-backend
+	      invert: true
+	    - files: ["config/app.yaml"]
+	      start-label: "# mock begins"
+	      end-label: "# mock ends"
+
+A block called mock is delimited by two lines containing "tgcom:start mock" and "tgcom:end mock", other labels can be
+given with start-label and end-label. The words tgcom:start and tgcom:end can be changed in the labels section of the
+configuration (see SetBlockLabels) */
+
+/* ProfileBlock is a block of a profile, in all the files matching Files (patterns relative to the configuration file) */
+type ProfileBlock struct {
+	Files      []string `yaml:"files,omitempty"`
+	Block      string   `yaml:"block,omitempty"`
+	StartLabel string   `yaml:"start-label,omitempty"`
+	EndLabel   string   `yaml:"end-label,omitempty"`
+	Invert     bool     `yaml:"invert,omitempty"`
+}
+
+/* ProfileResult tells what happened to a block of a profile in a file */
+type ProfileResult struct {
+	File    string     `json:"file"`
+	Block   string     `json:"block"`
+	State   BlockState `json:"state"`
+	Changed bool       `json:"changed"`
+}
+
+/* returns the labels that delimit the block called name */
+func BlockLabels(name string) (startLabel string, endLabel string) {
+	return BlockStartLabel + " " + name, BlockEndLabel + " " + name
+}
+
+/* returns the start and end label of the block */
+func (b ProfileBlock) Labels() (string, string) {
+	if b.Block != "" {
+		return BlockLabels(b.Block)
+	}
+	return b.StartLabel, b.EndLabel
+}
+
+/* enables or disables the profile called name: its blocks are uncommented (enable) or commented (disable) with
+ChangeFileLabel. Blocks that are already in the desired state are left untouched, so that enabling a profile twice
+does not comment its lines twice; the lines of a mixed block are brought in the desired state one by one with
+EnsureFileSelection, because commenting the whole block would comment twice its commented lines. Files are relative
+to baseDir */
+func SetProfile(config *Config, baseDir string, name string, enable bool, dryrun bool) ([]ProfileResult, error) {
+	blocks, ok := config.Profiles[name]
+	if !ok {
+		return nil, usageErrorf("profile %q not found", name)
+	}
+
+	var results []ProfileResult
+	for _, block := range blocks {
+		startLabel, endLabel := block.Labels()
+		if startLabel == "" || endLabel == "" {
+			return results, fmt.Errorf("profile %q: a block needs a name or both start-label and end-label", name)
+		}
+		want := Uncommented
+		if enable == block.Invert {
+			want = Commented
+		}
+
+		for _, pattern := range block.Files {
+			files, err := ExpandGlob(baseDir, pattern)
+			if err != nil {
+				return results, err
+			}
+			if len(files) == 0 {
+				return results, noMatchErrorf("profile %q: no file matches %s", name, pattern)
+			}
+			for _, file := range files {
+				state, err := LabelBlockState(file, startLabel, endLabel)
+				if err != nil {
+					return results, err
+				}
+				result := ProfileResult{File: file, Block: block.Block, State: state}
+				if block.Block == "" {
+					result.Block = startLabel
+				}
+				if state != want {
+					if err := setBlock(file, startLabel, endLabel, state, want, dryrun); err != nil {
+						return results, fmt.Errorf("%s: %w", file, err)
+					}
+					result.Changed = true
+					result.State = want
+				}
+				results = append(results, result)
+			}
+		}
+	}
+	return results, nil
+}
+
+/* brings the block between startLabel and endLabel of the file from the state it is in to the state want */
+func setBlock(filename string, startLabel string, endLabel string, state BlockState, want BlockState, dryrun bool) error {
+	if state == Mixed {
+		_, err := EnsureFileSelection(filename, Selection{StartLabel: startLabel, EndLabel: endLabel}, want, dryrun)
+		return err
+	}
+	action := ActionUncomment
+	if want == Commented {
+		action = ActionComment
+	}
+	return DefaultEngine.ChangeFileLabel(filename, startLabel, endLabel, action, dryrun)
+}
+
+/* returns the state of the lines between startLabel and endLabel in the file */
+func LabelBlockState(filename string, startLabel string, endLabel string) (BlockState, error) {
+	lines, err := readFileLines(filename)
+	if err != nil {
+		return "", err
+	}
+	chars, err := lineCommentChars(filename, lines)
+	if err != nil {
+		return "", err
+	}
+	selected, err := SelectLines(filename, lines, Selection{StartLabel: startLabel, EndLabel: endLabel})
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", filename, err)
+	}
+	return LinesState(lines, selected, chars), nil
+}
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetProfile(t *testing.T) {
+	dir := t.TempDir()
+	original := "package api\n// tgcom:start mock\nclient := mock()\n\nclient.Seed()\n// tgcom:end mock\n" +
+		"// tgcom:start real\n// client := dial()\n// tgcom:end real\n"
+	file := filepath.Join(dir, "client.go")
+	if err := os.WriteFile(file, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := &Config{Profiles: map[string][]ProfileBlock{
+		"mock": {
+			{Files: []string{"*.go"}, Block: "mock"},
+			{Files: []string{"*.go"}, Block: "real", Invert: true},
+		},
+	}}
+	read := func() string {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	disabled := "package api\n// tgcom:start mock\n// client := mock()\n// \n// client.Seed()\n// tgcom:end mock\n" +
+		"// tgcom:start real\nclient := dial()\n// tgcom:end real\n"
+	steps := []struct {
+		enable bool
+		want   string
+	}{
+		{false, disabled},
+		// disabling twice does not comment the lines twice
+		{false, disabled},
+		{true, original},
+		{true, original},
+	}
+	for i, step := range steps {
+		if _, err := SetProfile(config, dir, "mock", step.enable, false); err != nil {
+			t.Fatalf("step %d: %v", i+1, err)
+		}
+		if got := read(); got != step.want {
+			t.Errorf("step %d (enable %v): file\n%s\nwant\n%s", i+1, step.enable, got, step.want)
+		}
+	}
+
+	// the commented lines of a mixed block are not commented twice
+	mixed := "package api\n// tgcom:start mock\n// client := mock()\nclient.Seed()\n// tgcom:end mock\n" +
+		"// tgcom:start real\nclient := dial()\n// tgcom:end real\n"
+	if err := os.WriteFile(file, []byte(mixed), 0644); err != nil {
+		t.Fatal(err)
+	}
+	results, err := SetProfile(config, dir, "mock", false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "package api\n// tgcom:start mock\n// client := mock()\n// client.Seed()\n// tgcom:end mock\n" +
+		"// tgcom:start real\nclient := dial()\n// tgcom:end real\n"
+	if got := read(); got != want {
+		t.Errorf("mixed block disabled:\n%s\nwant\n%s", got, want)
+	}
+	if len(results) != 2 || !results[0].Changed || results[1].Changed {
+		t.Errorf("results %+v, want only the mock block changed", results)
+	}
+}
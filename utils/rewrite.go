@@ -0,0 +1,213 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+/* These functions do what ChangeFileLine and ChangeFileLabel do, but they return the errors instead of stopping the
+program, so that a command that modifies many files (e.g. tgcom apply) can go on and report what happened to each one */
+
+/* Engine reads and writes the files on FS and prints the dry runs on Output. The functions of the package that take
+a filename use DefaultEngine, so that the command line works on the files of the disk; a program can create its own
+engine, e.g. on a MemFS, to modify files that are not on disk. Backup tells whether a backup of a file is made before
+writing it and what to do with it after, the empty policy is BackupTemp; the backups go in BackupDir if it is not
+empty (see BackupPolicy) */
+type Engine struct {
+	FS        FS
+	Output    io.Writer
+	Backup    BackupPolicy
+	BackupDir string
+}
+
+/* engine used by the functions of the package */
+var DefaultEngine = &Engine{FS: OSFS{}, Output: os.Stdout}
+
+/* returns the lines of the file */
+func readFileLines(filename string) ([]string, error) {
+	return DefaultEngine.readLines(filename)
+}
+
+func (e *Engine) readLines(filename string) ([]string, error) {
+	file, err := e.FS.Open(filename)
+	if err != nil {
+		return nil, ioError(err)
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, ioError(scanner.Err())
+}
+
+/* replaces the content of the file with lines. As in ChangeFileLine the new content is written in a temporary file
+that is then renamed to the original file, with a backup made as the backup policy of the engine says */
+func writeFileLines(filename string, lines []string) error {
+	return DefaultEngine.writeLines(filename, lines)
+}
+
+func (e *Engine) writeLines(filename string, lines []string) error {
+	return e.rewrite(filename, func(_ io.Reader, w io.Writer) error {
+		writer := bufio.NewWriter(w)
+		for _, lineContent := range lines {
+			if _, err := writer.WriteString(lineContent + "\n"); err != nil {
+				return err
+			}
+		}
+		return writer.Flush()
+	})
+}
+
+/* replaces the content of the file with what write writes reading the current content. The temporary files left by a
+previous run that has been killed are removed and a backup of the file is created as BackupPolicy says. The new
+content goes in a temporary file with a unique name in the directory of the file, that is renamed to the file at the
+end: the file is replaced only by the rename, so if something goes wrong before the file is left as it was and the
+temporary file is removed. The errors are returned as IOError, unless write returns an error of another type */
+func (e *Engine) rewrite(filename string, write func(io.Reader, io.Writer) error) error {
+	started := time.Now()
+	e.removeStale(filename)
+
+	backupFilename, err := e.createBackup(filename)
+	if err != nil {
+		return ioError(err)
+	}
+	if backupFilename != "" {
+		Logger.Debug("backup created", "file", filename, "backup", backupFilename)
+	}
+
+	tmpFile, tmpFilename, err := e.FS.CreateTemp(filepath.Dir(filename), tempPattern(filename, tempSuffix))
+	if err != nil {
+		e.removeBackup(backupFilename)
+		return ioError(err)
+	}
+	Logger.Debug("writing temporary file", "file", filename, "temp", tmpFilename)
+	err = func() error {
+		input, err := e.FS.Open(filename)
+		if err != nil {
+			tmpFile.Close()
+			return err
+		}
+		defer input.Close()
+		if err := write(input, tmpFile); err != nil {
+			tmpFile.Close()
+			return err
+		}
+		if err := tmpFile.Close(); err != nil {
+			return err
+		}
+		return input.Close()
+	}()
+	if err == nil {
+		err = e.copyMode(filename, tmpFilename)
+	}
+	if err == nil {
+		err = e.FS.Rename(tmpFilename, filename)
+	}
+	if err != nil {
+		e.FS.Remove(tmpFilename)
+		e.removeBackup(backupFilename)
+		Logger.Warn("write failed, file left unchanged", "file", filename, "error", err)
+		return ioError(err)
+	}
+
+	if err := e.finishBackup(filename, backupFilename); err != nil {
+		return ioError(err)
+	}
+	Logger.Info("file written", "file", filename, "elapsed", time.Since(started))
+	return nil
+}
+
+/* gives to the temporary file the permissions of the file it replaces, if the filesystem has permissions */
+func (e *Engine) copyMode(filename string, tmpFilename string) error {
+	chmoder, ok := e.FS.(interface {
+		Chmod(name string, mode fs.FileMode) error
+	})
+	if !ok {
+		return nil
+	}
+	info, err := fs.Stat(e.FS, filename)
+	if err != nil {
+		return err
+	}
+	return chmoder.Chmod(tmpFilename, info.Mode().Perm())
+}
+
+/* reads the file, applies change to the selected lines (each one with its comment characters, or with the ones of
+language if it is not empty) and writes the file back. The file is written only if some line has changed, so that
+its modification time is kept when there is nothing to do. Returns the number of lines changed */
+func (e *Engine) changeFileLines(filename string, selection Selection, language string, dryrun bool, change func(string, string) string) (int, error) {
+	lines, err := e.readLines(filename)
+	if err != nil {
+		return 0, err
+	}
+	newLines, err := changeLines(filename, lines, selection, language, change)
+	if err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	for i := range lines {
+		if newLines[i] != lines[i] {
+			changed++
+			if dryrun {
+				fmt.Fprintf(e.Output, "%s:%d: %s -> %s\n", filename, i+1, lines[i], newLines[i])
+			}
+		}
+	}
+	if changed == 0 {
+		Logger.Info("lines already in the desired state", "file", filename)
+	}
+	if dryrun || changed == 0 {
+		return changed, nil
+	}
+	return changed, e.writeLines(filename, newLines)
+}
+
+/* same as changeFileLines, but on lines that have already been read. filename is used to choose the language */
+func changeLines(filename string, lines []string, selection Selection, language string, change func(string, string) string) ([]string, error) {
+	var chars []string
+	var err error
+	if language != "" {
+		char, err := LanguageCommentChars(language)
+		if err != nil {
+			return nil, err
+		}
+		chars = make([]string, len(lines))
+		for i := range chars {
+			chars[i] = char
+		}
+		Logger.Debug("language forced", "file", filename, "language", language, "comment", char)
+	} else if chars, err = lineCommentChars(filename, lines); err != nil {
+		return nil, err
+	} else {
+		logLanguage(filename)
+	}
+
+	selected, err := SelectLines(filename, lines, selection)
+	if err != nil {
+		return nil, err
+	}
+	logSelected(filename, selection, selected)
+	return changeSelectedLines(lines, selected, chars, indentedChange(filename, change)), nil
+}
+
+/* applies change to the lines for which selected is true, using the comment characters of each line. The returned
+slice is a new one, lines is not modified */
+func changeSelectedLines(lines []string, selected []bool, chars []string, change func(string, string) string) []string {
+	newLines := make([]string, len(lines))
+	for i, lineContent := range lines {
+		newLines[i] = lineContent
+		if selected[i] {
+			newLines[i] = change(lineContent, chars[i])
+		}
+	}
+	return newLines
+}
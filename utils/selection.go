@@ -0,0 +1,255 @@
+package utils
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+/* Selection tells which lines of a file must be modified. The fields can be combined: a line is selected if it is
+selected by every field that is not empty. Lines is a line or a range of lines like the -l flag (or a list of them
+separated by commas, e.g. "3-5,8"), the labels select the lines between them like -s and -e, Regex selects the lines
+matching a regular expression, Symbol selects the declaration of a function, method or class and Key the value of a
+key in a configuration file */
+type Selection struct {
+	Lines      string
+	StartLabel string
+	EndLabel   string
+	Regex      string
+	Symbol     string
+	Key        string
+}
+
+/* returns true if no field of the selection has been given */
+func (s Selection) IsEmpty() bool {
+	return s == Selection{}
+}
+
+/* returns, for each line of the file, true if the line is selected */
+func SelectLines(filename string, lines []string, selection Selection) ([]bool, error) {
+	if selection.IsEmpty() {
+		return nil, usageErrorf("empty selection: give lines, labels, a regex, a symbol or a key")
+	}
+	if (selection.StartLabel == "") != (selection.EndLabel == "") {
+		return nil, usageErrorf("both the start label and the end label must be given")
+	}
+
+	selected := make([]bool, len(lines))
+	for i := range selected {
+		selected[i] = true
+	}
+	keepRange := func(start, end int) {
+		for i := range selected {
+			if i+1 < start || i+1 > end {
+				selected[i] = false
+			}
+		}
+	}
+
+	if selection.Lines != "" {
+		// a list of ranges, e.g. "3-5,8", selects the lines of any of them
+		inRanges := make([]bool, len(lines))
+		for _, lineRange := range strings.Split(selection.Lines, ",") {
+			start, end, err := ParseLines(lineRange)
+			if err != nil {
+				return nil, err
+			}
+			if end > len(lines) {
+				return nil, noMatchErrorf("line number is out of range")
+			}
+			for i := start - 1; i < end; i++ {
+				inRanges[i] = true
+			}
+		}
+		for i := range selected {
+			selected[i] = selected[i] && inRanges[i]
+		}
+	}
+	if selection.StartLabel != "" {
+		inSection := false
+		found := false
+		for i, lineContent := range lines {
+			if strings.Contains(lineContent, selection.EndLabel) {
+				inSection = false
+			}
+			selected[i] = selected[i] && inSection
+			if strings.Contains(lineContent, selection.StartLabel) {
+				inSection = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, noMatchErrorf("start label %q not found", selection.StartLabel)
+		}
+	}
+	if selection.Regex != "" {
+		re, err := regexp.Compile(selection.Regex)
+		if err != nil {
+			return nil, usageErrorf("invalid regex: %v", err)
+		}
+		for i, lineContent := range lines {
+			selected[i] = selected[i] && re.MatchString(lineContent)
+		}
+	}
+	if selection.Symbol != "" {
+		start, end, err := FindSymbolLines(filename, lines, selection.Symbol)
+		if err != nil {
+			return nil, err
+		}
+		keepRange(start, end)
+	}
+	if selection.Key != "" {
+		start, end, err := FindKeyLines(filename, lines, selection.Key)
+		if err != nil {
+			return nil, err
+		}
+		keepRange(start, end)
+	}
+	return selected, nil
+}
+
+/* same as FindLines but returns an error instead of stopping the program */
+func ParseLines(lineStr string) (startLine int, endLine int, err error) {
+	var startStr, endStr string
+	if strings.Contains(lineStr, "-") {
+		parts := strings.Split(lineStr, "-")
+		if len(parts) != 2 {
+			return 0, 0, usageErrorf("invalid range format. Use 'start-end'")
+		}
+		startStr, endStr = parts[0], parts[1]
+	} else {
+		startStr, endStr = lineStr, lineStr
+	}
+	startLine, err = strconv.Atoi(startStr)
+	if err != nil || startLine <= 0 {
+		return 0, 0, usageErrorf("invalid start line number")
+	}
+	endLine, err = strconv.Atoi(endStr)
+	if err != nil || endLine < startLine {
+		return 0, 0, usageErrorf("invalid end line number")
+	}
+	return startLine, endLine, nil
+}
+
+/* keywords that introduce the declaration of a symbol in the supported languages */
+var declarationRegexp = `\b(func|function|def|defp|defmodule|class|fn|sub|type|struct|interface|enum|trait|impl|object|module|procedure|local function)\b`
+
+/* returns the first and last line (1-based) of the declaration of symbol. The end of the declaration is found with the
+indentation for python, with the "end" keyword for ruby, lua and elixir and by matching the braces for the other
+languages */
+func FindSymbolLines(filename string, lines []string, symbol string) (int, int, error) {
+	char, err := CommentCharsFor(filename)
+	if err != nil {
+		return 0, 0, err
+	}
+	start, end, err := findSymbol(filename, lines, symbol, char)
+	if err == nil {
+		return start, end, nil
+	}
+	// look for the symbol among the commented lines, so that a commented symbol can be uncommented
+	views := make([]string, len(lines))
+	for i, lineContent := range lines {
+		views[i] = Uncomment(lineContent, char)
+	}
+	if start, end, viewErr := findSymbol(filename, views, symbol, char); viewErr == nil {
+		return start, end, nil
+	}
+	return 0, 0, err
+}
+
+func findSymbol(filename string, lines []string, symbol string, char string) (int, int, error) {
+	re := regexp.MustCompile(declarationRegexp + `.*\b` + regexp.QuoteMeta(symbol) + `\b`)
+	start := -1
+	for i, lineContent := range lines {
+		if strings.HasPrefix(strings.TrimSpace(lineContent), char) {
+			continue
+		}
+		if loc := re.FindStringIndex(lineContent); loc != nil {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return 0, 0, noMatchErrorf("symbol %q not found", symbol)
+	}
+
+	switch filepath.Ext(filename) {
+	case ".py":
+		return start + 1, indentedBlockEnd(lines, start) + 1, nil
+	case ".rb", ".lua", ".ex", ".exs":
+		indent := indentation(lines[start])
+		for i := start + 1; i < len(lines); i++ {
+			if indentation(lines[i]) == indent && strings.TrimSpace(lines[i]) == "end" {
+				return start + 1, i + 1, nil
+			}
+		}
+		return 0, 0, noMatchErrorf("end of symbol %q not found", symbol)
+	}
+
+	// match the braces, ignoring the ones in strings and comments
+	depth := 0
+	opened := false
+	for i := start; i < len(lines); i++ {
+		inString := byte(0)
+		text := lines[i]
+		for k := 0; k < len(text); k++ {
+			c := text[k]
+			switch {
+			case inString != 0:
+				if c == '\\' {
+					k++
+				} else if c == inString {
+					inString = 0
+				}
+			case c == '"' || c == '\'' || c == '`':
+				inString = c
+			case strings.HasPrefix(text[k:], char):
+				k = len(text)
+			case c == '{':
+				depth++
+				opened = true
+			case c == '}':
+				depth--
+			}
+		}
+		if opened && depth <= 0 {
+			return start + 1, i + 1, nil
+		}
+		// a declaration without body, e.g. "type A int", unless its brace is on one of the next lines as in java, c#
+		// and c++ code ("class A" and "{" on the following line)
+		if !opened && i == start && !strings.HasSuffix(strings.TrimSpace(text), ")") &&
+			!strings.HasSuffix(strings.TrimSpace(text), "(") && !strings.HasSuffix(strings.TrimSpace(text), ",") &&
+			!braceFollows(lines, start, char) {
+			return start + 1, start + 1, nil
+		}
+	}
+	return 0, 0, noMatchErrorf("end of symbol %q not found", symbol)
+}
+
+/* returns true if the first line after start that is not blank or a comment opens a brace */
+func braceFollows(lines []string, start int, char string) bool {
+	for _, lineContent := range lines[start+1:] {
+		trimmed := strings.TrimSpace(lineContent)
+		if trimmed == "" || strings.HasPrefix(trimmed, char) || strings.HasPrefix(trimmed, "/*") || strings.HasPrefix(trimmed, "*") {
+			continue
+		}
+		return strings.HasPrefix(trimmed, "{")
+	}
+	return false
+}
+
+/* returns the last line of the block opened by line start, made of the following lines indented more than it */
+func indentedBlockEnd(lines []string, start int) int {
+	end := start
+	for i := start + 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "" {
+			continue
+		}
+		if indentation(lines[i]) <= indentation(lines[start]) {
+			break
+		}
+		end = i
+	}
+	return end
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindSymbolLines(t *testing.T) {
+	tests := []struct {
+		name      string
+		filename  string
+		src       string
+		symbol    string
+		wantStart int
+		wantEnd   int
+	}{
+		{"go function", "main.go", "package main\n\nfunc run() {\n\tif x {\n\t}\n}\n\nfunc other() {}\n", "run", 3, 6},
+		{"go one line", "main.go", "func other() {}\nfunc run() {}\n", "other", 1, 1},
+		{"go without body", "main.go", "type ID int\n\nfunc run() {\n}\n", "ID", 1, 1},
+		{"go multi-line signature", "main.go", "func run(\n\ta int,\n) {\n\treturn\n}\n", "run", 1, 5},
+		{"go brace in string", "main.go", "func run() {\n\ts := \"}\"\n}\n", "run", 1, 3},
+		{"java brace on the next line", "Main.java", "class Main\n{\n    void run() {\n    }\n}\n", "Main", 1, 5},
+		{"comments before the brace", "App.java", "class App\n// the application\n\n/* entry point */\n{\n}\nclass Other {}\n", "App", 1, 6},
+		{"c++ struct", "point.cpp", "struct Point\n{\n    int x;\n};\n", "Point", 1, 4},
+		{"declaration followed by code", "main.go", "type ID int\nvar x = map[string]int{}\n", "ID", 1, 1},
+		{"python", "main.py", "def run():\n    x = 1\n\n    return x\nprint(run())\n", "run", 1, 4},
+		{"ruby", "main.rb", "def run\n  1\nend\nrun\n", "run", 1, 3},
+		{"commented symbol", "main.go", "// func run() {\n// }\n", "run", 1, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lines := strings.Split(strings.TrimSuffix(test.src, "\n"), "\n")
+			start, end, err := FindSymbolLines(test.filename, lines, test.symbol)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if start != test.wantStart || end != test.wantEnd {
+				t.Errorf("lines %d-%d, want %d-%d", start, end, test.wantStart, test.wantEnd)
+			}
+		})
+	}
+}
+
+func TestFindSymbolLinesNotFound(t *testing.T) {
+	lines := []string{"func run() {", "}"}
+	if _, _, err := FindSymbolLines("main.go", lines, "missing"); err == nil {
+		t.Error("no error for a missing symbol")
+	}
+	if _, _, err := FindSymbolLines("main.go", []string{"func run() {"}, "run"); err == nil {
+		t.Error("no error for a body that is never closed")
+	}
+}
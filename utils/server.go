@@ -0,0 +1,301 @@
+package utils
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+/* Server is the HTTP API of tgcom serve, that lets other programs (e.g. a dashboard) read and flip the toggles of a
+checkout. Every path is relative to Root and cannot leave it, not even through symbolic links. When Token is set the
+requests must carry it in the header "Authorization: Bearer TOKEN". The endpoints are:
+
+	GET  /v1/blocks?path=DIR    the blocks of the files under DIR (the whole root by default), like tgcom status
+	POST /v1/preview            the operations in the body (a manifest in JSON), with the diff of every file
+	POST /v1/apply              the operations in the body are executed, like tgcom apply
+
+The responses have the same JSON schema of the reports of tgcom status and tgcom apply (--format json); errors are
+returned as {"error": "message"}.
+
+The server usually listens on the loopback interface without a token, so it must not obey the web pages opened in a
+browser of the same machine: the POST requests must have the content type application/json, that a page can only send
+to another site with the permission of CORS, and when Addr is set the Host and Origin headers must name the address
+the server listens on, so that a page cannot reach it through a domain resolved to 127.0.0.1 (DNS rebinding) */
+type Server struct {
+	Root  string
+	Token string
+	Addr  string // the address the server listens on (e.g. 127.0.0.1:8080), empty to accept any Host and Origin
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex // a lock for every file written, the requests are handled concurrently
+}
+
+/* the body of a request cannot be bigger than this */
+const maxRequestSize = 1 << 20
+
+/* returns a server for the files under root */
+func NewServer(root string, token string) (*Server, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	if abs, err = filepath.EvalSymlinks(abs); err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+	return &Server{Root: abs, Token: token}, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := s.checkOrigin(r); err != nil {
+		writeJSONError(w, http.StatusForbidden, err)
+		return
+	}
+	if s.Token != "" {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSONError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+			return
+		}
+	}
+
+	switch r.URL.Path {
+	case "/v1/blocks":
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, fmt.Errorf("use GET for %s", r.URL.Path))
+			return
+		}
+		s.handleBlocks(w, r)
+	case "/v1/preview", "/v1/apply":
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, fmt.Errorf("use POST for %s", r.URL.Path))
+			return
+		}
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+			writeJSONError(w, http.StatusUnsupportedMediaType, errors.New("the body must have the content type application/json"))
+			return
+		}
+		s.handleOperations(w, r, r.URL.Path == "/v1/apply")
+	default:
+		writeJSONError(w, http.StatusNotFound, fmt.Errorf("unknown endpoint %s", r.URL.Path))
+	}
+}
+
+func (s *Server) handleBlocks(w http.ResponseWriter, r *http.Request) {
+	paths := r.URL.Query()["path"]
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	var dirs []string
+	for _, path := range paths {
+		dir, err := s.resolve(path)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		dirs = append(dirs, dir)
+	}
+
+	blocks, _ := ScanBlocks(dirs, "", "")
+	if blocks == nil {
+		blocks = []Block{}
+	}
+	for i := range blocks {
+		blocks[i].File = s.relative(blocks[i].File)
+	}
+	writeJSON(w, http.StatusOK, blocks)
+}
+
+/* executes the operations of the manifest in the body of the request, or only computes their diffs */
+func (s *Server) handleOperations(w http.ResponseWriter, r *http.Request, apply bool) {
+	var manifest Manifest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&manifest); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %v", err))
+		return
+	}
+	for i, op := range manifest.Operations {
+		if op.Name == "" {
+			manifest.Operations[i].Name = fmt.Sprintf("operation %d", i+1)
+		}
+		for _, pattern := range op.Files {
+			if err := checkRelative(pattern); err != nil {
+				writeJSONError(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+	}
+
+	results := make([]OperationResult, 0, len(manifest.Operations))
+	for _, op := range manifest.Operations {
+		op := op
+		results = append(results, op.run(s.Root, func(file string, action Action) FileResult {
+			return s.changeFile(op, file, action, apply)
+		}))
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+/* applies the operation to a file, or computes only its diff */
+func (s *Server) changeFile(op Operation, file string, action Action, apply bool) FileResult {
+	result := FileResult{File: s.relative(file)}
+	if _, err := s.resolve(result.File); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	var changed int
+	var err error
+	if apply {
+		unlock := s.lockFile(file)
+		changed, err = ChangeFileSelection(file, op.selection(), action, op.Language, false)
+		unlock()
+	} else {
+		var lines, newLines []string
+		if lines, err = readFileLines(file); err == nil {
+			if newLines, err = ChangeLines(file, lines, op.selection(), action, op.Language); err == nil {
+				for i := range lines {
+					if lines[i] != newLines[i] {
+						changed++
+					}
+				}
+				result.Diff = UnifiedDiff(filepath.ToSlash(result.File), lines, newLines)
+			}
+		}
+	}
+	result.Changed = changed
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+/* locks the file until the returned function is called, so that two requests do not write it at the same time */
+func (s *Server) lockFile(file string) func() {
+	s.mu.Lock()
+	if s.locks == nil {
+		s.locks = map[string]*sync.Mutex{}
+	}
+	lock, ok := s.locks[file]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.locks[file] = lock
+	}
+	s.mu.Unlock()
+	lock.Lock()
+	return lock.Unlock
+}
+
+/* returns an error if the Host or the Origin of the request is not the address of the server, see Server */
+func (s *Server) checkOrigin(r *http.Request) error {
+	if s.Addr == "" {
+		return nil
+	}
+	if !s.isServerHost(r.Host) {
+		return fmt.Errorf("invalid host %q", r.Host)
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || !s.isServerHost(u.Host) {
+			return fmt.Errorf("requests from %s are not allowed", origin)
+		}
+	}
+	return nil
+}
+
+/* returns true if hostport (a Host header or the host of an Origin) names the address of the server: its host and
+port, or localhost and the loopback addresses if the server listens on them or on every interface. A server on every
+interface accepts also its IP addresses, that cannot be rebound like a domain name */
+func (s *Server) isServerHost(hostport string) bool {
+	addrHost, addrPort, err := net.SplitHostPort(s.Addr)
+	if err != nil {
+		return false
+	}
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host, port = hostport, "80"
+	}
+	host = strings.ToLower(strings.Trim(host, "[]"))
+	if port != addrPort || host == "" {
+		return false
+	}
+	if host == strings.ToLower(addrHost) {
+		return true
+	}
+	addrIP := net.ParseIP(addrHost)
+	everyInterface := addrHost == "" || (addrIP != nil && addrIP.IsUnspecified())
+	isLoopback := host == "localhost" || (net.ParseIP(host) != nil && net.ParseIP(host).IsLoopback())
+	switch {
+	case isLoopback:
+		return everyInterface || addrHost == "localhost" || (addrIP != nil && addrIP.IsLoopback())
+	case everyInterface:
+		return net.ParseIP(host) != nil
+	}
+	return false
+}
+
+/* returns the absolute path of a path relative to the root, or an error if it is outside the root */
+func (s *Server) resolve(path string) (string, error) {
+	if err := checkRelative(path); err != nil {
+		return "", err
+	}
+	abs := filepath.Join(s.Root, path)
+	real, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return "", fmt.Errorf("%s: no such file or directory", path)
+	}
+	if real != s.Root && !strings.HasPrefix(real, s.Root+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the root directory", path)
+	}
+	return abs, nil
+}
+
+/* returns path relative to the root */
+func (s *Server) relative(path string) string {
+	if rel, err := filepath.Rel(s.Root, path); err == nil {
+		return rel
+	}
+	return path
+}
+
+/* rejects absolute paths and paths that go up from the root */
+func checkRelative(path string) error {
+	if filepath.IsAbs(path) || strings.HasPrefix(path, "/") || filepath.VolumeName(path) != "" {
+		return fmt.Errorf("%s: paths must be relative to the root directory", path)
+	}
+	for _, part := range strings.Split(filepath.ToSlash(path), "/") {
+		if part == ".." {
+			return fmt.Errorf("%s is outside the root directory", path)
+		}
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,207 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+/* returns a server on a root with main.go, and a directory outside the root with secret.go, linked from the root as
+escape */
+func newTestServer(t *testing.T, token string) (*Server, string) {
+	t.Helper()
+	base := t.TempDir()
+	root := filepath.Join(base, "root")
+	outside := filepath.Join(base, "outside")
+	for _, dir := range []string{root, outside} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\nx := 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "secret.go"), []byte("package secret\nx := 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Skipf("symbolic links not supported: %v", err)
+	}
+	server, err := NewServer(root, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, outside
+}
+
+func serve(server *Server, method string, target string, body string, token string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	if method == http.MethodPost {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestServerRejectsPathsOutsideRoot(t *testing.T) {
+	server, _ := newTestServer(t, "")
+	for _, path := range []string{"..", "../outside", "a/../../outside", "/etc", "escape"} {
+		response := serve(server, http.MethodGet, "/v1/blocks?path="+path, "", "")
+		if response.Code != http.StatusBadRequest {
+			t.Errorf("GET /v1/blocks?path=%s: status %d, want %d (%s)", path, response.Code, http.StatusBadRequest, response.Body)
+		}
+	}
+	if response := serve(server, http.MethodGet, "/v1/blocks?path=.", "", ""); response.Code != http.StatusOK {
+		t.Errorf("GET /v1/blocks?path=.: status %d (%s)", response.Code, response.Body)
+	}
+
+	for _, pattern := range []string{"../outside/secret.go", "/tmp/*.go"} {
+		body := `{"operations": [{"files": ["` + pattern + `"], "lines": "2", "action": "comment"}]}`
+		if response := serve(server, http.MethodPost, "/v1/apply", body, ""); response.Code != http.StatusBadRequest {
+			t.Errorf("POST /v1/apply on %s: status %d, want %d", pattern, response.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestServerDoesNotFollowSymlinksOutsideRoot(t *testing.T) {
+	server, outside := newTestServer(t, "")
+	body := `{"operations": [{"files": ["escape/secret.go"], "lines": "2", "action": "comment"}]}`
+	response := serve(server, http.MethodPost, "/v1/apply", body, "")
+	if response.Code != http.StatusOK {
+		t.Fatalf("status %d (%s)", response.Code, response.Body)
+	}
+	var results []OperationResult
+	if err := json.Unmarshal(response.Body.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || len(results[0].Files) != 1 || !strings.Contains(results[0].Files[0].Error, "outside the root") {
+		t.Errorf("results %+v, want an error for escape/secret.go", results)
+	}
+	if data, _ := os.ReadFile(filepath.Join(outside, "secret.go")); string(data) != "package secret\nx := 1\n" {
+		t.Errorf("file outside the root modified: %q", data)
+	}
+}
+
+func TestServerToken(t *testing.T) {
+	server, _ := newTestServer(t, "s3cret")
+	for _, token := range []string{"", "wrong", "s3cret2"} {
+		response := serve(server, http.MethodGet, "/v1/blocks", "", token)
+		if response.Code != http.StatusUnauthorized {
+			t.Errorf("token %q: status %d, want %d", token, response.Code, http.StatusUnauthorized)
+		}
+	}
+	if response := serve(server, http.MethodGet, "/v1/blocks", "", "s3cret"); response.Code != http.StatusOK {
+		t.Errorf("valid token: status %d (%s)", response.Code, response.Body)
+	}
+}
+
+func TestServerPreviewDoesNotWrite(t *testing.T) {
+	server, _ := newTestServer(t, "")
+	body := `{"operations": [{"files": ["main.go"], "lines": "2", "action": "comment"}]}`
+	response := serve(server, http.MethodPost, "/v1/preview", body, "")
+	if response.Code != http.StatusOK || !strings.Contains(response.Body.String(), `+// x := 1`) {
+		t.Errorf("status %d, body %s", response.Code, response.Body)
+	}
+	if data, _ := os.ReadFile(filepath.Join(server.Root, "main.go")); string(data) != "package main\nx := 1\n" {
+		t.Errorf("preview modified main.go: %q", data)
+	}
+}
+
+/* a web page can send a text/plain POST or use a domain resolved to 127.0.0.1, the server must reject both */
+func TestServerRejectsCrossSiteRequests(t *testing.T) {
+	server, _ := newTestServer(t, "")
+	server.Addr = "127.0.0.1:8080"
+	body := `{"operations": [{"files": ["main.go"], "lines": "2", "action": "comment"}]}`
+	tests := []struct {
+		name        string
+		method      string
+		host        string
+		origin      string
+		contentType string
+		want        int
+	}{
+		{"text/plain", http.MethodPost, "127.0.0.1:8080", "", "text/plain", http.StatusUnsupportedMediaType},
+		{"form", http.MethodPost, "127.0.0.1:8080", "", "application/x-www-form-urlencoded", http.StatusUnsupportedMediaType},
+		{"rebound domain", http.MethodGet, "evil.example:8080", "", "", http.StatusForbidden},
+		{"other port", http.MethodGet, "127.0.0.1:9090", "", "", http.StatusForbidden},
+		{"cross-site origin", http.MethodPost, "127.0.0.1:8080", "http://evil.example", "application/json", http.StatusForbidden},
+		{"null origin", http.MethodPost, "127.0.0.1:8080", "null", "application/json", http.StatusForbidden},
+		{"localhost", http.MethodGet, "localhost:8080", "", "", http.StatusOK},
+		{"same origin", http.MethodPost, "127.0.0.1:8080", "http://localhost:8080", "application/json; charset=utf-8", http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			target := "/v1/blocks"
+			if test.method == http.MethodPost {
+				target = "/v1/preview"
+			}
+			request := httptest.NewRequest(test.method, target, strings.NewReader(body))
+			request.Host = test.host
+			if test.origin != "" {
+				request.Header.Set("Origin", test.origin)
+			}
+			if test.contentType != "" {
+				request.Header.Set("Content-Type", test.contentType)
+			}
+			response := httptest.NewRecorder()
+			server.ServeHTTP(response, request)
+			if response.Code != test.want {
+				t.Errorf("status %d, want %d (%s)", response.Code, test.want, response.Body)
+			}
+		})
+	}
+}
+
+func TestServerHosts(t *testing.T) {
+	tests := []struct {
+		addr string
+		host string
+		want bool
+	}{
+		{"127.0.0.1:8080", "127.0.0.1:8080", true},
+		{"127.0.0.1:8080", "[::1]:8080", true},
+		{"localhost:8080", "LOCALHOST:8080", true},
+		{":8080", "localhost:8080", true},
+		{":8080", "192.168.1.2:8080", true},
+		{":8080", "evil.example:8080", false},
+		{"0.0.0.0:80", "10.0.0.1", true},
+		{"192.168.1.2:8080", "localhost:8080", false},
+		{"127.0.0.1:8080", "127.0.0.1", false},
+	}
+	for _, test := range tests {
+		server := &Server{Addr: test.addr}
+		if got := server.isServerHost(test.host); got != test.want {
+			t.Errorf("server on %s, host %s: %v, want %v", test.addr, test.host, got, test.want)
+		}
+	}
+}
+
+/* the requests are handled concurrently, the writes of the same file must not overwrite each other */
+func TestServerConcurrentApply(t *testing.T) {
+	server, _ := newTestServer(t, "")
+	body := `{"operations": [{"files": ["main.go"], "lines": "2", "action": "toggle"}]}`
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if response := serve(server, http.MethodPost, "/v1/apply", body, ""); response.Code != http.StatusOK {
+				t.Errorf("status %d (%s)", response.Code, response.Body)
+			}
+		}()
+	}
+	wg.Wait()
+	// an even number of toggles gives back the file
+	if data, _ := os.ReadFile(filepath.Join(server.Root, "main.go")); string(data) != "package main\nx := 1\n" {
+		t.Errorf("main.go after 100 toggles: %q", data)
+	}
+}
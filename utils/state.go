@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"strings"
+)
+
+/* BlockState tells whether the lines of a block are all commented, all uncommented or some of both */
+type BlockState string
+
+const (
+	Commented   BlockState = "commented"
+	Uncommented BlockState = "uncommented"
+	Mixed       BlockState = "mixed"
+)
+
+/* returns true if the line is commented with char */
+func IsCommented(line string, char string) bool {
+	_, _, _, ok := commentedBody(line, char)
+	return ok
+}
+
+/* returns the state of the selected lines, each one with its comment characters. Blank lines are ignored: a block
+without other lines is uncommented */
+func LinesState(lines []string, selected []bool, chars []string) BlockState {
+	commented, uncommented := 0, 0
+	for i, lineContent := range lines {
+		if !selected[i] || strings.TrimSpace(lineContent) == "" {
+			continue
+		}
+		if IsCommented(lineContent, chars[i]) {
+			commented++
+		} else {
+			uncommented++
+		}
+	}
+	switch {
+	case commented > 0 && uncommented > 0:
+		return Mixed
+	case commented > 0:
+		return Commented
+	}
+	return Uncommented
+}
+
+/* returns the comment characters of every line of the file: they are the same for all the lines, except for the files
+with regions in other languages (see embedded.go) */
+func lineCommentChars(filename string, lines []string) ([]string, error) {
+	chars := make([]string, len(lines))
+	if IsEmbedded(filename) {
+		for i, lang := range RegionLanguages(filename, lines) {
+			chars[i] = CommentChars[lang]
+		}
+		return chars, nil
+	}
+	char, err := CommentCharsFor(filename)
+	if err != nil {
+		return nil, err
+	}
+	for i := range chars {
+		chars[i] = char
+	}
+	return chars, nil
+}
+
+/* Take in input a file, the lines to modify, the state they must have (Commented or Uncommented) and dryrun. Only the
+lines that are not already in that state are modified, so that running it twice gives the same result, and if all
+the lines are already in that state the file is not written at all. Blank lines are left as they are. Returns the
+number of lines modified */
+func EnsureFileSelection(filename string, selection Selection, want BlockState, dryrun bool) (int, error) {
+	return DefaultEngine.EnsureFileSelection(filename, selection, want, dryrun)
+}
+
+/* same as the function EnsureFileSelection, but on the filesystem of the engine */
+func (e *Engine) EnsureFileSelection(filename string, selection Selection, want BlockState, dryrun bool) (int, error) {
+	if want != Commented && want != Uncommented {
+		return 0, usageErrorf("invalid state %q: use commented or uncommented", want)
+	}
+	return e.changeFileLines(filename, selection, "", dryrun, ensureChange(want))
+}
+
+/* same as EnsureFileSelection, but on lines that have already been read */
+func EnsureLines(filename string, lines []string, selection Selection, want BlockState) ([]string, error) {
+	if want != Commented && want != Uncommented {
+		return nil, usageErrorf("invalid state %q: use commented or uncommented", want)
+	}
+	return changeLines(filename, lines, selection, "", ensureChange(want))
+}
+
+/* returns the function that brings a line in the state want */
+func ensureChange(want BlockState) func(string, string) string {
+	return func(lineContent string, char string) string {
+		if strings.TrimSpace(lineContent) == "" || IsCommented(lineContent, char) == (want == Commented) {
+			return lineContent
+		}
+		if want == Commented {
+			return Comment(lineContent, char)
+		}
+		return Uncomment(lineContent, char)
+	}
+}
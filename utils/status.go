@@ -0,0 +1,173 @@
+package utils
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+/* Block is a block of lines delimited by a start and an end label. Start and End are the lines (1-based) of the two
+labels, the block is made of the lines between them */
+type Block struct {
+	File  string     `json:"file"`
+	Name  string     `json:"name"`
+	Start int        `json:"start"`
+	End   int        `json:"end"`
+	State BlockState `json:"state"`
+}
+
+/* LabelError is a label without its pair */
+type LabelError struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (e *LabelError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message)
+}
+
+/* words that start and end the labels of the named blocks, followed by the name of the block */
+var BlockStartLabel = "tgcom:start"
+var BlockEndLabel = "tgcom:end"
+
+var startLabelRegexp = blockLabelRegexp(BlockStartLabel)
+var endLabelRegexp = blockLabelRegexp(BlockEndLabel)
+
+/* changes the words that start and end the labels of the named blocks, e.g. "region" and "endregion" */
+func SetBlockLabels(start string, end string) {
+	BlockStartLabel, BlockEndLabel = start, end
+	startLabelRegexp, endLabelRegexp = blockLabelRegexp(start), blockLabelRegexp(end)
+}
+
+/* the label must not be the end of a longer word, so that "region" does not match "endregion" */
+func blockLabelRegexp(label string) *regexp.Regexp {
+	return regexp.MustCompile(`(?:^|[^\w:-])` + regexp.QuoteMeta(label) + `\s+(\S+)`)
+}
+
+/* returns the blocks of the lines of a file. Without labels the named blocks (see BlockLabels) are returned, each one
+ending at the first end label with the same name; otherwise the blocks between startLabel and endLabel. Labels
+without their pair are returned as errors */
+func FindBlocks(filename string, lines []string, startLabel string, endLabel string) ([]Block, []error) {
+	chars, err := lineCommentChars(filename, lines)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	var blocks []Block
+	var errs []error
+	open := map[string]int{} // line of the start label of the blocks not closed yet
+	var names []string       // names of the open blocks, in order
+	for i, lineContent := range lines {
+		var startName, endName string
+		if startLabel == "" {
+			if m := endLabelRegexp.FindStringSubmatch(lineContent); m != nil {
+				endName = m[1]
+			}
+			if m := startLabelRegexp.FindStringSubmatch(lineContent); m != nil {
+				startName = m[1]
+			}
+		} else {
+			if strings.Contains(lineContent, endLabel) {
+				endName = startLabel
+			}
+			if strings.Contains(lineContent, startLabel) {
+				startName = startLabel
+			}
+		}
+
+		if endName != "" {
+			if start, ok := open[endName]; ok {
+				delete(open, endName)
+				blocks = append(blocks, Block{File: filename, Name: endName, Start: start + 1, End: i + 1})
+			} else if startLabel == "" {
+				errs = append(errs, &LabelError{File: filename, Line: i + 1, Message: fmt.Sprintf("end label of block %q without start label", endName)})
+			}
+		}
+		if startName != "" {
+			if _, ok := open[startName]; ok {
+				errs = append(errs, &LabelError{File: filename, Line: i + 1, Message: fmt.Sprintf("block %q started again before its end label", startName)})
+			} else {
+				names = append(names, startName)
+			}
+			open[startName] = i
+		}
+	}
+	for _, name := range names {
+		if start, ok := open[name]; ok {
+			errs = append(errs, &LabelError{File: filename, Line: start + 1, Message: fmt.Sprintf("start label of block %q without end label", name)})
+			delete(open, name)
+		}
+	}
+
+	for i := range blocks {
+		selected := make([]bool, len(lines))
+		for j := blocks[i].Start; j < blocks[i].End-1; j++ {
+			selected[j] = true
+		}
+		blocks[i].State = LinesState(lines, selected, chars)
+	}
+	sort.SliceStable(blocks, func(i, j int) bool { return blocks[i].Start < blocks[j].Start })
+	return blocks, errs
+}
+
+/* returns the blocks of all the files in paths, directories are read recursively (see CollectFiles) */
+func ScanBlocks(paths []string, startLabel string, endLabel string) ([]Block, []error) {
+	files, errs := CollectFiles(paths)
+	var blocks []Block
+	for _, path := range files {
+		lines, err := readFileLines(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		fileBlocks, fileErrs := FindBlocks(path, lines, startLabel, endLabel)
+		blocks = append(blocks, fileBlocks...)
+		errs = append(errs, fileErrs...)
+	}
+	return blocks, errs
+}
+
+/* returns the files in paths that are not ignored by IgnorePatterns. Directories are read recursively, skipping the
+.git directory and the files of unsupported languages; files given explicitly are returned whatever their language */
+func CollectFiles(paths []string) ([]string, []error) {
+	var files []string
+	var errs []error
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !info.IsDir() {
+			files = append(files, withoutIgnored([]string{path})...)
+			continue
+		}
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if d.Name() == ".git" || (p != path && IsIgnored(p)) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if IsIgnored(p) {
+				return nil
+			}
+			if _, err := CommentCharsFor(p); err == nil {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return files, errs
+}
@@ -0,0 +1,12 @@
+# golden fixture for Bash
+x=$(compute 1)
+    echo "$x"
+# set -x
+
+y=2
+# tgcom-golden-start
+DEBUG=1
+    echo "$y"
+# DEBUG=0
+# tgcom-golden-end
+exit 0
@@ -0,0 +1,12 @@
+// golden fixture for C++/C
+int x = compute(1);
+    return x;
+// printf("%d\n", x);
+
+int y = 2;
+// tgcom-golden-start
+debug = 1;
+    printf("%d\n", y);
+// debug = 0;
+// tgcom-golden-end
+return y;
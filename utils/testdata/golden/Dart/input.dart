@@ -0,0 +1,12 @@
+// golden fixture for Dart
+var x = compute(1);
+    return x;
+// print(x);
+
+var y = 2;
+// tgcom-golden-start
+debug = true;
+    print(y);
+// debug = false;
+// tgcom-golden-end
+main();
@@ -0,0 +1,12 @@
+# golden fixture for Elixir
+x = compute(1)
+    IO.puts(x)
+# IO.inspect(x)
+
+y = 2
+# tgcom-golden-start
+debug = true
+    IO.puts(y)
+# debug = false
+# tgcom-golden-end
+main()
@@ -0,0 +1,12 @@
+% golden fixture for Erlang
+X = compute(1),
+    io:format("~p~n", [X]),
+% erlang:display(X),
+
+Y = 2,
+% tgcom-golden-start
+Debug = true,
+    io:format("~p~n", [Y]),
+% Debug = false,
+% tgcom-golden-end
+ok.
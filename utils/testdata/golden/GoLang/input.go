@@ -0,0 +1,12 @@
+// golden fixture for GoLang
+x := compute(1)
+    return x
+// fmt.Println(x)
+
+y := 2
+// tgcom-golden-start
+debug = true
+    log.Print(y)
+// debug = false
+// tgcom-golden-end
+fmt.Println(y)
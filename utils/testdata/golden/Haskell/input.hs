@@ -0,0 +1,12 @@
+-- golden fixture for Haskell
+x = compute 1
+    return x
+-- print x
+
+y = 2
+-- tgcom-golden-start
+debug = True
+    print y
+-- debug = False
+-- tgcom-golden-end
+main = print y
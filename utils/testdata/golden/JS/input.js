@@ -0,0 +1,12 @@
+// golden fixture for JS
+let x = compute(1);
+    return x;
+// console.log(x);
+
+const y = 2;
+// tgcom-golden-start
+debug = true;
+    console.log(y);
+// debug = false;
+// tgcom-golden-end
+export default y;
@@ -0,0 +1,12 @@
+// golden fixture for Java
+int x = compute(1);
+    return x;
+// System.out.println(x);
+
+int y = 2;
+// tgcom-golden-start
+debug = true;
+    System.out.println(y);
+// debug = false;
+// tgcom-golden-end
+return y;
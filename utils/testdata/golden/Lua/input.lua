@@ -0,0 +1,12 @@
+-- golden fixture for Lua
+local x = compute(1)
+    return x
+-- print(x)
+
+local y = 2
+-- tgcom-golden-start
+debug = true
+    print(y)
+-- debug = false
+-- tgcom-golden-end
+main()
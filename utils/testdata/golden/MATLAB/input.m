@@ -0,0 +1,12 @@
+% golden fixture for MATLAB
+x = compute(1);
+    disp(x);
+% plot(x);
+
+y = 2;
+% tgcom-golden-start
+debug = true;
+    disp(y);
+% debug = false;
+% tgcom-golden-end
+exit;
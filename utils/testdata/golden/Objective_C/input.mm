@@ -0,0 +1,12 @@
+// golden fixture for Objective-C
+int x = compute(1);
+    return x;
+// NSLog(@"%d", x);
+
+int y = 2;
+// tgcom-golden-start
+debug = YES;
+    NSLog(@"%d", y);
+// debug = NO;
+// tgcom-golden-end
+return y;
@@ -0,0 +1,12 @@
+<?php
+$x = compute(1);
+    return $x;
+// echo $x;
+
+$y = 2;
+// tgcom-golden-start
+$debug = true;
+    echo $y;
+// $debug = false;
+// tgcom-golden-end
+exit(0);
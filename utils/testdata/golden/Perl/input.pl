@@ -0,0 +1,12 @@
+# golden fixture for Perl
+my $x = compute(1);
+    return $x;
+# print "$x\n";
+
+my $y = 2;
+# tgcom-golden-start
+$debug = 1;
+    print "$y\n";
+# $debug = 0;
+# tgcom-golden-end
+exit 0;
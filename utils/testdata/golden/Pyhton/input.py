@@ -0,0 +1,12 @@
+# golden fixture for Pyhton
+x = compute(1)
+    return x
+# print(x)
+
+y = 2
+# tgcom-golden-start
+DEBUG = True
+    print(y)
+# DEBUG = False
+# tgcom-golden-end
+main()
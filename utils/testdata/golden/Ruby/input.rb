@@ -0,0 +1,12 @@
+# golden fixture for Ruby
+x = compute(1)
+    return x
+# puts x
+
+y = 2
+# tgcom-golden-start
+$debug = true
+    puts y
+# $debug = false
+# tgcom-golden-end
+main
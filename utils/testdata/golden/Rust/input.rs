@@ -0,0 +1,12 @@
+// golden fixture for Rust
+let x = compute(1);
+    return x;
+// println!("{}", x);
+
+let y = 2;
+// tgcom-golden-start
+debug = true;
+    println!("{}", y);
+// debug = false;
+// tgcom-golden-end
+main();
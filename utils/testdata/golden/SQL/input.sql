@@ -0,0 +1,12 @@
+-- golden fixture for SQL
+SELECT id FROM users;
+    WHERE active = 1;
+-- DELETE FROM users;
+
+SELECT 2;
+-- tgcom-golden-start
+SET debug = 1;
+    SELECT * FROM logs;
+-- SET debug = 0;
+-- tgcom-golden-end
+COMMIT;
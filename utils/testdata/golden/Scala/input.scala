@@ -0,0 +1,12 @@
+// golden fixture for Scala
+val x = compute(1)
+    return x
+// println(x)
+
+val y = 2
+// tgcom-golden-start
+debug = true
+    println(y)
+// debug = false
+// tgcom-golden-end
+main()
@@ -0,0 +1,12 @@
+-- golden fixture for VHDL
+x <= compute(a);
+    y <= x;
+-- report "x";
+
+z <= '1';
+-- tgcom-golden-start
+debug <= '1';
+    q <= z;
+-- debug <= '0';
+-- tgcom-golden-end
+end architecture;
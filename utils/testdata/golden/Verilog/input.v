@@ -0,0 +1,12 @@
+// golden fixture for Verilog
+assign x = a & b;
+    assign y = x;
+// $display(x);
+
+assign z = 1;
+// tgcom-golden-start
+debug = 1;
+    $display(z);
+// debug = 0;
+// tgcom-golden-end
+endmodule
@@ -0,0 +1,13 @@
+<p>golden fixture for php blocks in html</p>
+<?php
+$debug = true;
+// echo $debug;
+?>
+<p>debug</p>
+<?php echo $x; ?>
+<!-- tgcom-golden-start -->
+<?php
+$verbose = true;
+?>
+<!-- tgcom-golden-end -->
+<p>last</p>
@@ -0,0 +1,154 @@
+package utils
+
+import (
+	"sort"
+	"strings"
+)
+
+/* Transform is the API for programs that already have the content in memory (linters, code generators): it takes the
+content and returns the new one, without reading or writing files. The selection is the same of the command line and
+the action is a typed value */
+
+/* Action is what is done to the selected lines */
+type Action string
+
+const (
+	ActionComment   Action = "comment"
+	ActionUncomment Action = "uncomment"
+	ActionToggle    Action = "toggle"
+)
+
+/* returns the action called name, as written in the -a flag and in the manifests */
+func ParseAction(name string) (Action, error) {
+	action := Action(name)
+	if err := checkAction(action); err != nil {
+		return "", err
+	}
+	return action, nil
+}
+
+/* returns an error if action is not comment, uncomment or toggle */
+func checkAction(action Action) error {
+	if action != ActionComment && action != ActionUncomment && action != ActionToggle {
+		return usageErrorf("invalid action %q: use comment, uncomment or toggle", action)
+	}
+	return nil
+}
+
+/* Language is a language supported by tgcom, its value is a key of CommentChars (e.g. "GoLang") */
+type Language string
+
+/* returns the language called name: a key of CommentChars or a name used in markdown code blocks (e.g. "python") */
+func ParseLanguage(name string) (Language, error) {
+	if _, ok := CommentChars[name]; ok {
+		return Language(name), nil
+	}
+	if key, ok := fenceLanguages[strings.ToLower(name)]; ok {
+		return Language(key), nil
+	}
+	return "", usageErrorf("unsupported language: %s", name)
+}
+
+/* returns the comment characters of the language */
+func (l Language) CommentChars() string {
+	return CommentChars[string(l)]
+}
+
+/* returns a file name with an extension of the language, used to select symbols and keys, whose syntax depends on
+the extension */
+func (l Language) filename() string {
+	var extensions []string
+	for extension, language := range extensionLanguages {
+		if language == l {
+			extensions = append(extensions, extension)
+		}
+	}
+	if len(extensions) == 0 {
+		return "transform"
+	}
+	sort.Strings(extensions)
+	return "transform" + extensions[0]
+}
+
+/* LineChange is a line modified by Transform, Line is 1-based */
+type LineChange struct {
+	Line int    `json:"line"`
+	Old  string `json:"old"`
+	New  string `json:"new"`
+}
+
+/* Report tells which lines Transform has modified */
+type Report struct {
+	Changed int          `json:"changed"`
+	Lines   []LineChange `json:"lines"`
+}
+
+/* applies action to the lines of src selected by sel, using the comment characters of lang for every line, and returns
+the new content. The line endings (LF or CRLF) and the final newline of src are kept */
+func Transform(src []byte, lang Language, sel Selection, action Action) ([]byte, Report, error) {
+	if _, ok := CommentChars[string(lang)]; !ok {
+		return nil, Report{}, usageErrorf("unsupported language: %s", lang)
+	}
+	return transform(lang.filename(), src, sel, action, func(lines []string) ([]string, error) {
+		chars := make([]string, len(lines))
+		for i := range chars {
+			chars[i] = lang.CommentChars()
+		}
+		return chars, nil
+	})
+}
+
+/* same as Transform, but the language is chosen with the extension of filename, like for the files of the command line:
+in html, vue, svelte, php, markdown and reStructuredText files each line gets the language of its region */
+func TransformFile(filename string, src []byte, sel Selection, action Action) ([]byte, Report, error) {
+	return transform(filename, src, sel, action, func(lines []string) ([]string, error) {
+		return lineCommentChars(filename, lines)
+	})
+}
+
+func transform(filename string, src []byte, sel Selection, action Action, commentChars func([]string) ([]string, error)) ([]byte, Report, error) {
+	if err := checkAction(action); err != nil {
+		return nil, Report{}, err
+	}
+
+	content := string(src)
+	finalNewline := strings.HasSuffix(content, "\n")
+	content = strings.TrimSuffix(content, "\n")
+	var lines []string
+	if content != "" || finalNewline {
+		lines = strings.Split(content, "\n")
+	}
+	crlf := make([]bool, len(lines))
+	for i, lineContent := range lines {
+		if strings.HasSuffix(lineContent, "\r") {
+			lines[i], crlf[i] = strings.TrimSuffix(lineContent, "\r"), true
+		}
+	}
+
+	chars, err := commentChars(lines)
+	if err != nil {
+		return nil, Report{}, err
+	}
+	selected, err := SelectLines(filename, lines, sel)
+	if err != nil {
+		return nil, Report{}, err
+	}
+	newLines := changeSelectedLines(lines, selected, chars, actionChange(filename, action))
+
+	report := Report{Lines: []LineChange{}}
+	var b strings.Builder
+	for i, lineContent := range newLines {
+		if lineContent != lines[i] {
+			report.Lines = append(report.Lines, LineChange{Line: i + 1, Old: lines[i], New: lineContent})
+		}
+		b.WriteString(lineContent)
+		if crlf[i] {
+			b.WriteString("\r")
+		}
+		if i < len(newLines)-1 || finalNewline {
+			b.WriteString("\n")
+		}
+	}
+	report.Changed = len(report.Lines)
+	return []byte(b.String()), report, nil
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTransformKeepsLineEndings(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"lf", "a\nb\nc\n", "a\n# b\nc\n"},
+		{"crlf", "a\r\nb\r\nc\r\n", "a\r\n# b\r\nc\r\n"},
+		{"mixed", "a\r\nb\nc\r\n", "a\r\n# b\nc\r\n"},
+		{"no final newline", "a\nb\nc", "a\n# b\nc"},
+		{"crlf without final newline", "a\r\nb\r\nc", "a\r\n# b\r\nc"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, report, err := Transform([]byte(test.src), "Bash", Selection{Lines: "2"}, ActionComment)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.want {
+				t.Errorf("Transform = %q, want %q", got, test.want)
+			}
+			want := Report{Changed: 1, Lines: []LineChange{{Line: 2, Old: "b", New: "# b"}}}
+			if !reflect.DeepEqual(report, want) {
+				t.Errorf("report %+v, want %+v", report, want)
+			}
+		})
+	}
+}
+
+func TestTransformFileUsesRegions(t *testing.T) {
+	src := "<script>\r\nlet a = 1;\r\n</script>\r\n<p>x</p>"
+	got, _, err := TransformFile("page.html", []byte(src), Selection{Lines: "2-4"}, ActionComment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "<script>\r\n// let a = 1;\r\n<!-- </script> -->\r\n<!-- <p>x</p> -->"
+	if string(got) != want {
+		t.Errorf("TransformFile = %q, want %q", got, want)
+	}
+}
+
+func TestTransformEmptySource(t *testing.T) {
+	got, report, err := Transform(nil, "GoLang", Selection{Regex: "x"}, ActionToggle)
+	if err != nil || len(got) != 0 || report.Changed != 0 {
+		t.Errorf("Transform(nil) = %q, %+v, %v", got, report, err)
+	}
+}
+
+func TestTransformTypedErrors(t *testing.T) {
+	src := []byte("a\nb\n")
+	var usage *UsageError
+	var noMatch *NoMatchError
+	if _, _, err := Transform(src, "Klingon", Selection{Lines: "1"}, ActionComment); !errors.As(err, &usage) {
+		t.Errorf("unknown language: %v (%T), want a UsageError", err, err)
+	}
+	if _, _, err := Transform(src, "Bash", Selection{Lines: "1"}, Action("delete")); !errors.As(err, &usage) {
+		t.Errorf("unknown action: %v (%T), want a UsageError", err, err)
+	}
+	if _, _, err := Transform(src, "Bash", Selection{Lines: "5"}, ActionComment); !errors.As(err, &noMatch) {
+		t.Errorf("line out of range: %v (%T), want a NoMatchError", err, err)
+	}
+	if _, _, err := Transform(src, "Bash", Selection{StartLabel: "begin", EndLabel: "end"}, ActionComment); !errors.As(err, &noMatch) {
+		t.Errorf("missing label: %v (%T), want a NoMatchError", err, err)
+	}
+	if _, _, err := TransformFile("file.unknown", src, Selection{Lines: "1"}, ActionComment); !errors.As(err, &usage) {
+		t.Errorf("unsupported extension: %v (%T), want a UsageError", err, err)
+	}
+}
@@ -0,0 +1,256 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+/* Watch keeps a profile in its state while the files change, e.g. when a code generator overwrites them and drops the
+toggles. The directories of the files of the profile are watched (with inotify on Linux, polling elsewhere or with
+Poll) rather than the files themselves, because many tools replace a file with a new one instead of writing it. The
+events are debounced: the profile is set again only when no event arrives for Debounce */
+
+/* Watcher reports the paths of the files created, written, moved or removed in the directories added to it */
+type Watcher interface {
+	Add(dir string) error
+	Events() <-chan string
+	Errors() <-chan error
+	Close() error
+}
+
+/* WatchOptions are the options of WatchProfile. Interval is used only by the polling watcher */
+type WatchOptions struct {
+	Poll     bool
+	Interval time.Duration
+	Debounce time.Duration
+}
+
+/* returns a watcher that uses inotify when it is available, or that polls the directories every interval */
+func NewWatcher(poll bool, interval time.Duration) (Watcher, error) {
+	if !poll {
+		if w, err := newNativeWatcher(); err == nil {
+			return w, nil
+		}
+	}
+	return newPollWatcher(interval), nil
+}
+
+/* sets the profile called name (like SetProfile) every time one of its files changes, until stop is closed. report is
+called with the results of every run, the first one when the watch starts */
+func WatchProfile(config *Config, baseDir string, name string, enable bool, options WatchOptions, stop <-chan struct{}, report func([]ProfileResult, error)) error {
+	if _, ok := config.Profiles[name]; !ok {
+		return usageErrorf("profile %q not found", name)
+	}
+	watcher, err := NewWatcher(options.Poll, options.Interval)
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	watched := map[string]bool{}
+	run := func() map[string]bool {
+		report(SetProfile(config, baseDir, name, enable, false))
+		files, dirs := profileFiles(config, baseDir, name)
+		for _, dir := range dirs {
+			if watched[dir] {
+				continue
+			}
+			if err := watcher.Add(dir); err != nil {
+				report(nil, err)
+				continue
+			}
+			watched[dir] = true
+		}
+		return files
+	}
+	files := run()
+
+	var timer *time.Timer
+	var fire <-chan time.Time
+	for {
+		select {
+		case <-stop:
+			return nil
+		case err := <-watcher.Errors():
+			report(nil, err)
+		case path := <-watcher.Events():
+			if !files[filepath.Clean(path)] && !matchesProfile(config, baseDir, name, path) {
+				continue
+			}
+			if timer == nil {
+				timer = time.NewTimer(options.Debounce)
+			} else {
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(options.Debounce)
+			}
+			fire = timer.C
+		case <-fire:
+			fire = nil
+			files = run()
+		}
+	}
+}
+
+/* returns the files of the profile and the directories to watch: the ones of the files and the ones before the first
+wildcard of the patterns, where new files can appear */
+func profileFiles(config *Config, baseDir string, name string) (map[string]bool, []string) {
+	files := map[string]bool{}
+	dirSet := map[string]bool{}
+	var dirs []string
+	addDir := func(dir string) {
+		if !dirSet[dir] {
+			dirSet[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	for _, block := range config.Profiles[name] {
+		for _, pattern := range block.Files {
+			addDir(patternDir(baseDir, pattern))
+			matches, _ := ExpandGlob(baseDir, pattern)
+			for _, file := range matches {
+				files[filepath.Clean(file)] = true
+				addDir(filepath.Dir(file))
+			}
+		}
+	}
+	return files, dirs
+}
+
+/* returns true if path matches one of the patterns of the profile, e.g. a file just created by a generator */
+func matchesProfile(config *Config, baseDir string, name string, path string) bool {
+	for _, block := range config.Profiles[name] {
+		for _, pattern := range block.Files {
+			if !filepath.IsAbs(pattern) {
+				pattern = filepath.Join(baseDir, pattern)
+			}
+			parts := strings.Split(filepath.ToSlash(filepath.Clean(pattern)), "/")
+			if ok, _ := matchParts(parts, strings.Split(filepath.ToSlash(filepath.Clean(path)), "/")); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/* returns the directory before the first wildcard of pattern */
+func patternDir(baseDir string, pattern string) string {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(baseDir, pattern)
+	}
+	if i := strings.IndexAny(pattern, "*?["); i >= 0 {
+		return filepath.Dir(pattern[:i] + "x")
+	}
+	return filepath.Dir(pattern)
+}
+
+/* pollWatcher finds the changes comparing the size and the modification time of the files of the directories every
+interval */
+type pollWatcher struct {
+	mu     sync.Mutex
+	dirs   map[string]map[string]os.FileInfo
+	events chan string
+	errors chan error
+	done   chan struct{}
+}
+
+func newPollWatcher(interval time.Duration) *pollWatcher {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	w := &pollWatcher{
+		dirs:   map[string]map[string]os.FileInfo{},
+		events: make(chan string),
+		errors: make(chan error),
+		done:   make(chan struct{}),
+	}
+	go w.loop(interval)
+	return w
+}
+
+func (w *pollWatcher) Add(dir string) error {
+	files, err := scanDir(dir)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.dirs[dir] = files
+	w.mu.Unlock()
+	return nil
+}
+
+func (w *pollWatcher) Events() <-chan string { return w.events }
+
+func (w *pollWatcher) Errors() <-chan error { return w.errors }
+
+func (w *pollWatcher) Close() error {
+	close(w.done)
+	return nil
+}
+
+func (w *pollWatcher) loop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+
+		var changed []string
+		w.mu.Lock()
+		for dir, before := range w.dirs {
+			after, err := scanDir(dir)
+			if err != nil {
+				// the directory has been removed, every file in it is gone
+				after = map[string]os.FileInfo{}
+			}
+			for name, info := range after {
+				if old, ok := before[name]; !ok || old.Size() != info.Size() || !old.ModTime().Equal(info.ModTime()) {
+					changed = append(changed, filepath.Join(dir, name))
+				}
+			}
+			for name := range before {
+				if _, ok := after[name]; !ok {
+					changed = append(changed, filepath.Join(dir, name))
+				}
+			}
+			w.dirs[dir] = after
+		}
+		w.mu.Unlock()
+
+		for _, path := range changed {
+			select {
+			case w.events <- path:
+			case <-w.done:
+				return
+			}
+		}
+	}
+}
+
+/* returns the regular files of dir with their size and modification time */
+func scanDir(dir string) (map[string]os.FileInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]os.FileInfo{}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			files[entry.Name()] = info
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,110 @@
+//go:build linux
+
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+/* inotifyWatcher uses the inotify API of Linux. The file descriptor is non-blocking, so that it can be read through an
+os.File and closing it stops the reading goroutine */
+type inotifyWatcher struct {
+	fd     int
+	file   *os.File
+	mu     sync.Mutex
+	dirs   map[int32]string
+	events chan string
+	errors chan error
+	done   chan struct{}
+}
+
+/* events that mean that the content of a file in the directory may have changed */
+const inotifyMask = syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO | syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MOVED_FROM
+
+func newNativeWatcher() (Watcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	w := &inotifyWatcher{
+		fd:     fd,
+		file:   os.NewFile(uintptr(fd), "inotify"),
+		dirs:   map[int32]string{},
+		events: make(chan string),
+		errors: make(chan error),
+		done:   make(chan struct{}),
+	}
+	go w.loop()
+	return w, nil
+}
+
+func (w *inotifyWatcher) Add(dir string) error {
+	// w.file.Fd() would put the file descriptor back in blocking mode
+	wd, err := syscall.InotifyAddWatch(w.fd, dir, inotifyMask)
+	if err != nil {
+		return &os.PathError{Op: "inotify_add_watch", Path: dir, Err: err}
+	}
+	w.mu.Lock()
+	w.dirs[int32(wd)] = dir
+	w.mu.Unlock()
+	return nil
+}
+
+func (w *inotifyWatcher) Events() <-chan string { return w.events }
+
+func (w *inotifyWatcher) Errors() <-chan error { return w.errors }
+
+func (w *inotifyWatcher) Close() error {
+	close(w.done)
+	return w.file.Close()
+}
+
+func (w *inotifyWatcher) loop() {
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			if errors.Is(err, os.ErrClosed) {
+				return
+			}
+			select {
+			case w.errors <- err:
+			case <-w.done:
+			}
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + syscall.SizeofInotifyEvent
+			name := strings.TrimRight(string(buf[nameStart:nameStart+int(event.Len)]), "\x00")
+			offset = nameStart + int(event.Len)
+
+			if event.Mask&syscall.IN_Q_OVERFLOW != 0 {
+				select {
+				case w.errors <- errors.New("inotify: too many events, some changes may have been missed"):
+				case <-w.done:
+					return
+				}
+				continue
+			}
+			w.mu.Lock()
+			dir, ok := w.dirs[event.Wd]
+			w.mu.Unlock()
+			if !ok || name == "" {
+				continue
+			}
+			select {
+			case w.events <- filepath.Join(dir, name):
+			case <-w.done:
+				return
+			}
+		}
+	}
+}
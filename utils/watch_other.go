@@ -0,0 +1,10 @@
+//go:build !linux
+
+package utils
+
+import "errors"
+
+/* there is no native watcher outside Linux: NewWatcher falls back to polling */
+func newNativeWatcher() (Watcher, error) {
+	return nil, errors.New("file notifications are not supported on this system")
+}